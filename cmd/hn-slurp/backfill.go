@@ -0,0 +1,72 @@
+package main
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/jaytaylor/hn-utils/common"
+	"github.com/jaytaylor/hn-utils/domain"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+var (
+	BackfillFrom       string
+	BackfillTo         string
+	BackfillCheckpoint string
+)
+
+func init() {
+	backfillCmd.Flags().StringVarP(&BackfillFrom, "from", "", "", "First day to collect, in YYYY-MM-DD form")
+	backfillCmd.Flags().StringVarP(&BackfillTo, "to", "", "", "Last day to collect (inclusive), in YYYY-MM-DD form")
+	backfillCmd.Flags().StringVarP(&BackfillCheckpoint, "checkpoint", "", "", "File to save progress to after each day; re-running with the same range resumes after the last completed day")
+	backfillCmd.MarkFlagRequired("from")
+	backfillCmd.MarkFlagRequired("to")
+
+	rootCmd.AddCommand(backfillCmd)
+}
+
+var backfillCmd = &cobra.Command{
+	Use:   "backfill",
+	Short: "Download the historical front pages of a range of days",
+	Long:  "Walks the historical front page (/front?day=YYYY-MM-DD) of each day in the range, tagging every story with the day and rank it appeared at.  -m/--max-stories applies per day.",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, _ []string) error {
+		from, err := time.Parse(common.FrontDayLayout, BackfillFrom)
+		if err != nil {
+			return fmt.Errorf("invalid --from %q: must be a date in YYYY-MM-DD form", BackfillFrom)
+		}
+		to, err := time.Parse(common.FrontDayLayout, BackfillTo)
+		if err != nil {
+			return fmt.Errorf("invalid --to %q: must be a date in YYYY-MM-DD form", BackfillTo)
+		}
+
+		crawler, err := newCrawler(cmd.Context(), "")
+		if err != nil {
+			return err
+		}
+
+		backfill := &common.Backfill{
+			Crawler:    crawler,
+			From:       from,
+			To:         to,
+			Checkpoint: BackfillCheckpoint,
+		}
+		stories, err := backfill.Run(cmd.Context())
+		if err != nil {
+			if !common.IsInterrupted(err) {
+				// Keep the days completed so far, then still fail.
+				if emitErr := emit(domain.NewDatabase(stories)); emitErr != nil {
+					log.Errorf("Emitting the %v stories collected before the failure: %s", len(stories), emitErr)
+				}
+				return err
+			}
+			log.Warnf("Backfill interrupted, keeping the %v stories of the days completed so far", len(stories))
+		} else if WithComments {
+			common.FetchDiscussionsLogged(cmd.Context(), crawler.GetDocument, stories, Workers, states)
+		}
+
+		return emit(domain.NewDatabase(stories))
+	},
+}
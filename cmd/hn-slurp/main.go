@@ -1,17 +1,18 @@
 package main
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
-	"net/http"
 	"net/http/cookiejar"
+	"os"
 	"strings"
+	"time"
 
 	"github.com/jaytaylor/hn-utils/common"
 	"github.com/jaytaylor/hn-utils/domain"
 
-	"github.com/PuerkitoBio/goquery"
 	log "github.com/sirupsen/logrus"
 	"github.com/spf13/cobra"
 	yaml "gopkg.in/yaml.v2"
@@ -19,6 +20,8 @@ import (
 
 var (
 	ID           string
+	Date         string
+	Site         string
 	MaxStories   int
 	OutputFormat string
 	Password     string
@@ -27,41 +30,63 @@ var (
 	Quiet        bool
 	User         string
 	Verbose      bool
-
-	// TODO: Add "comments", "story", but will require updates to support
-	//       threaded structure.
-	Sections = map[string]string{
-		"ask": "/ask",
-		//"comments":    "/threads?id=%v",
-		"favorites": "/favorites?id=%v",
-		"frontpage": "/",
-		//"story":        "/item?id=%v",
-		"new":         "/newest",
-		"show":        "/show",
-		"submissions": "/submitted?id=%v",
-		"upvotes":     "/upvoted?id=%v",
-	}
+	MinDelay     time.Duration
+	Retries      int
+	Backoff      time.Duration
+	MaxBackoff   time.Duration
+	SessionFile  string
+	Polls        bool
+	WithComments bool
+	Workers      int
+	States       string
+	KnownRun     int
+	Reconcile    bool
+	Prune        bool
+	Since        string
+
+	PasswordCommand string
+	PasswordFile    string
+	PasswordPrompt  bool
+
+	since  time.Time                 // Parsed Since.
+	states common.CommentStatePolicy // Parsed States.
 )
 
 func init() {
-	areas := []string{}
-	for s, _ := range Sections {
-		areas = append(areas, s)
-	}
-
 	rootCmd.PersistentFlags().StringVarP(&User, "user", "u", "jaytaylor", "HN username to login as")
-	rootCmd.PersistentFlags().StringVarP(&Password, "password", "p", "", "HN login password")
-	rootCmd.PersistentFlags().StringVarP(&ID, "id", "i", "", "Relevant user or story identifier")
+	rootCmd.PersistentFlags().StringVarP(&Password, "password", "p", "", "HN login password (visible in shell history and process listings, prefer one of the alternatives below)")
+	rootCmd.PersistentFlags().StringVarP(&PasswordCommand, "password-command", "", "", "Credential helper command whose output is the HN password (the username is passed via $HN_USER)")
+	rootCmd.PersistentFlags().StringVarP(&PasswordFile, "password-file", "", "", "File containing the HN password")
+	rootCmd.PersistentFlags().BoolVarP(&PasswordPrompt, "password-prompt", "", false, "Prompt for the HN password on the terminal; password sources in order of precedence are: --password, --password-command, --password-file, $"+common.PasswordEnvVar+", --password-prompt")
+	rootCmd.PersistentFlags().StringVarP(&ID, "id", "i", "", "HN user whose items to get, for the comments, favorites, submissions and upvotes sections")
+	rootCmd.PersistentFlags().StringVarP(&Date, "date", "", "", "Day in YYYY-MM-DD form, for the front section (historical front pages)")
+	rootCmd.PersistentFlags().StringVarP(&Site, "site", "", "", "Domain name, for the site section (stories from a site)")
 	rootCmd.PersistentFlags().StringVarP(&OutputFormat, "output", "o", "json", `Output format, one of "json", "yaml"`)
 	rootCmd.PersistentFlags().IntVarP(&MaxStories, "max-stories", "m", -1, "Maximum number of stories to collect")
 	rootCmd.PersistentFlags().StringVarP(&ReadExisting, "existing", "e", "", `Load an existing array of stories from named JSON database file, then front-load new content (set to "-" to read from STDIN)`)
-	rootCmd.PersistentFlags().StringVarP(&Section, "section", "s", "frontpage", fmt.Sprintf("Site area to get paged results for.  Available selections: %v", strings.Join(areas, ", ")))
+	rootCmd.PersistentFlags().IntVarP(&KnownRun, "stop-after-known", "", common.DefaultStopAfterKnown, "With --existing, stop once this many consecutive items are already in the database (known items seen along the way have their points and comment counts refreshed)")
+	rootCmd.PersistentFlags().BoolVarP(&Reconcile, "reconcile", "", false, "With --existing, crawl the entire listing and mark stories no longer in it (e.g. unfavorited) with a removed_at time")
+	rootCmd.PersistentFlags().BoolVarP(&Prune, "prune", "", false, "With --reconcile, drop stories no longer in the listing instead of marking them")
+	rootCmd.PersistentFlags().StringVarP(&Section, "section", "s", "frontpage", fmt.Sprintf("Site area to get paged results for.  Available selections: %v", strings.Join(sectionNames(), ", ")))
 	rootCmd.PersistentFlags().BoolVarP(&Quiet, "quiet", "q", false, "Activate quiet log output")
 	rootCmd.PersistentFlags().BoolVarP(&Verbose, "verbose", "v", false, "Activate verbose log output")
+	rootCmd.PersistentFlags().DurationVarP(&MinDelay, "delay", "", 500*time.Millisecond, "Minimum delay between HTTP requests")
+	rootCmd.PersistentFlags().IntVarP(&Retries, "retries", "", 5, "Maximum number of retries for rate-limited or failed HTTP requests")
+	rootCmd.PersistentFlags().DurationVarP(&Backoff, "backoff", "", 2*time.Second, "Initial retry backoff delay, doubled after each failed attempt")
+	rootCmd.PersistentFlags().DurationVarP(&MaxBackoff, "max-backoff", "", time.Minute, "Maximum retry backoff delay")
+	rootCmd.PersistentFlags().StringVarP(&SessionFile, "session-file", "", common.DefaultSessionStorePath(), "File to save login sessions in for reuse by later runs (set to empty string to disable)")
+	rootCmd.PersistentFlags().BoolVarP(&WithComments, "with-comments", "", false, "Also fetch the full discussion of each collected story")
+	rootCmd.PersistentFlags().IntVarP(&Workers, "workers", "", common.DefaultDiscussionWorkers, "Number of discussions to fetch concurrently with --with-comments (requests remain subject to --delay)")
+	rootCmd.PersistentFlags().StringVarP(&States, "comment-states", "", "mark", `What to do with dead, flagged and deleted comments in discussions, one of: "mark" (keep with status fields set), "include" (keep as shown, placeholder text included), "drop"`)
+	rootCmd.Flags().StringVarP(&Since, "since", "", "", "Stop upon reaching an item submitted before this day (YYYY-MM-DD) or time (RFC 3339), for sections listing items newest first: "+strings.Join(chronologicalSectionNames(), ", "))
+	rootCmd.PersistentFlags().BoolVarP(&Polls, "polls", "", false, "Only keep poll items, fetching the current scores of their options (the item page of every self-post is fetched to tell polls apart, and polls in the --existing database are refreshed too)")
 }
 
 func main() {
-	if err := rootCmd.Execute(); err != nil {
+	ctx, cancel := common.InterruptContext(context.Background())
+	defer cancel()
+
+	if err := rootCmd.ExecuteContext(ctx); err != nil {
 		log.Fatal(err)
 	}
 }
@@ -70,150 +95,234 @@ var rootCmd = &cobra.Command{
 	Use:   "hn-slurp",
 	Short: "Download the specified section from HN and transform it into structured JSON",
 	Long:  "Retrieves objects as an array of structured Story object for a given HN user/password combination.  The 'user upvotes' section has a hard requirement for user/password login.",
-	PreRunE: func(_ *cobra.Command, _ []string) error {
+	PersistentPreRunE: func(cmd *cobra.Command, _ []string) error {
 		common.InitLogging(Quiet, Verbose)
 
-		// Validate section.
-		var found bool
-		for s, _ := range Sections {
-			if strings.ToLower(Section) == s {
-				found = true
-				break
-			}
+		passwords := common.PasswordSources{
+			Flag:    Password,
+			Command: PasswordCommand,
+			File:    PasswordFile,
+			Prompt:  PasswordPrompt,
 		}
-		if !found {
-			return errors.New("Missing required flag: -s/--section must not be empty, see --help for a lis of valid secitions")
+		var err error
+		if Password, err = passwords.Resolve(cmd.Context(), User); err != nil {
+			return err
 		}
-
-		// Require password supplied when collecting user upvotes.
-		if Section == "upvotes" && Password == "" {
-			return errors.New("Missing required flag: -p/--password must not be empty")
+		if states, err = common.ParseCommentStatePolicy(States); err != nil {
+			return err
 		}
 
-		// Validate ID.
-		if strings.Contains(Sections[Section], "%v") {
-			if ID == "" {
-				return fmt.Errorf("Missing required flag: -i/--id must not be empty for section=%v", Section)
-			}
+		common.Transport = &common.Throttle{
+			MinInterval: MinDelay,
+			MaxRetries:  Retries,
+			Backoff:     Backoff,
+			MaxBackoff:  MaxBackoff,
 		}
 		return nil
 	},
-	RunE: func(cmd *cobra.Command, args []string) error {
-		var (
-			moreLink        = fmt.Sprintf("%v%v", common.BaseURL, Sections[Section])
-			stories         = domain.Stories{}
-			existingStories domain.Stories
-			existingID      int64 = -1 // Used for picking up where an existing collection ends.
-		)
-
-		if strings.Contains(moreLink, "%v") {
-			// Fill in ID param.
-			moreLink = fmt.Sprintf(moreLink, ID)
+	PreRunE: func(cmd *cobra.Command, _ []string) error {
+		// Validate section and its parameters.
+		Section = strings.ToLower(Section)
+		section, ok := Sections[Section]
+		if !ok {
+			return fmt.Errorf("Invalid -s/--section %q, must be one of: %v", Section, strings.Join(sectionNames(), ", "))
+		}
+		if _, err := section.URL(Section, paramValues()); err != nil {
+			return err
 		}
 
-		if ReadExisting != "" {
+		if Since != "" {
+			if !section.Chronological {
+				return fmt.Errorf("--since requires a section listing items newest first, one of: %v", strings.Join(chronologicalSectionNames(), ", "))
+			}
 			var err error
-			if existingStories, err = common.LoadStories(ReadExisting); err != nil {
-				return err
-			} else if len(existingStories) > 0 {
-				existingID = existingStories[0].ID
+			if since, err = common.ParseTime(Since); err != nil {
+				return fmt.Errorf("Invalid --since: %s", err)
 			}
 		}
 
-		client, err := getClient()
+		return checkReconcileFlags()
+	},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		startURL, err := Sections[Section].URL(Section, paramValues())
 		if err != nil {
 			return err
 		}
-		log.Debug("Logged in successfully")
 
-		for len(moreLink) > 0 {
-			log.WithField("more-link", moreLink).Debug("Fetching")
+		crawler, err := newCrawler(cmd.Context(), startURL)
+		if err != nil {
+			return err
+		}
+		crawler.Since = since
 
-			rc, err := common.CheckedGet(client, moreLink)
-			if err != nil {
+		var existing domain.Database
+		if ReadExisting != "" {
+			if existing, err = common.LoadDatabase(ReadExisting); err != nil {
 				return err
 			}
+		}
 
-			doc, err := goquery.NewDocumentFromReader(rc)
-			if err != nil {
-				return err
-			}
-			if err := rc.Close(); err != nil {
-				return fmt.Errorf("closing response body from %v: %s", moreLink, err)
-			}
+		var db domain.Database
+		if Section == "comments" {
+			db, err = crawlComments(cmd.Context(), crawler, existing)
+		} else {
+			db, err = crawlStories(cmd.Context(), crawler, existing)
+		}
+		if err != nil {
+			return err
+		}
 
-			var caughtUp bool
+		return emit(db)
+	},
+}
 
-			doc.Find(".athing").EachWithBreak(func(i int, s *goquery.Selection) bool {
-				if caughtUp {
-					return false
-				}
+// emit prints db to STDOUT in the selected output format.
+func emit(db domain.Database) error {
+	switch OutputFormat {
+	case "json":
+		bs, err := json.MarshalIndent(db, "", "    ")
+		if err != nil {
+			return err
+		}
+		fmt.Print(string(bs))
 
-				story := common.ExtractStory(s)
+	case "yaml":
+		bs, err := yaml.Marshal(db)
+		if err != nil {
+			return err
+		}
+		fmt.Print(string(bs))
 
-				if story.ID == existingID {
-					log.WithField("story-id", story.ID).Debug("Caught up to newest story in pre-existing data")
-					caughtUp = true
-					stories = append(stories, existingStories...)
-				}
+	default:
+		return fmt.Errorf("unrecognized output format %q", OutputFormat)
+	}
+	return nil
+}
 
-				stories = append(stories, story)
+// crawlStories collects the stories of the section's listing, merging them
+// into the existing ones.
+func crawlStories(ctx context.Context, crawler *common.Crawler, existing domain.Database) (domain.Database, error) {
+	if !Reconcile {
+		crawler.Known = common.KnownIDs(existing.Stories)
+		crawler.StopAfterKnown = KnownRun
+	}
 
-				return true
-			})
+	stories, _, err := crawler.Stories(ctx)
+	if err != nil {
+		if !common.IsInterrupted(err) && !errors.Is(err, common.ErrEmptyPage) {
+			return domain.Database{}, err
+		}
+		log.Warnf("Crawl incomplete (%s), keeping the %v stories collected so far", err, len(stories))
+	}
+	if WithComments && err == nil {
+		common.FetchDiscussionsLogged(ctx, crawler.GetDocument, stories, Workers, states)
+	}
+	if stories, err = mergeOrReconcile(stories, existing.Stories, err == nil); err != nil {
+		return domain.Database{}, err
+	}
 
-			if caughtUp {
-				break
+	if Polls {
+		var failures map[int64]error
+		if stories, failures = common.PollTallies(ctx, crawler.GetDocument, stories); len(failures) > 0 {
+			for id, err := range failures {
+				log.WithField("story-id", id).Warnf("Fetching poll tally failed: %s", err)
 			}
+			log.Warnf("Fetching %v poll tallies failed, those polls keep their previous tallies", len(failures))
+		}
+	}
 
-			moreLink = doc.Find(".morelink").Last().AttrOr("href", "")
-			if len(moreLink) > 0 && !strings.HasPrefix(moreLink, "https://") {
-				moreLink = fmt.Sprintf("%s/%s", common.BaseURL, moreLink)
-			}
+	return domain.NewDatabase(stories), nil
+}
 
-			if MaxStories != -1 && len(stories) >= MaxStories {
-				break
-			}
-		}
+// mergeOrReconcile merges the crawled stories into the existing ones, or with
+// --reconcile reconciles the two and prints the summary of changes to STDERR.
+// An incomplete crawl is merged with a warning instead of being reconciled.
+func mergeOrReconcile(stories domain.Stories, existing domain.Stories, complete bool) (domain.Stories, error) {
+	if !Reconcile {
+		return common.MergeStories(stories, existing), nil
+	}
+	if !complete {
+		log.Warn("Crawl incomplete, skipping reconciliation")
+		return common.MergeStories(stories, existing), nil
+	}
 
-		switch OutputFormat {
-		case "json":
-			bs, err := json.MarshalIndent(stories, "", "    ")
-			if err != nil {
-				return err
-			}
-			fmt.Print(string(bs))
+	reconciled, summary, err := common.ReconcileStories(stories, existing, time.Now().UTC(), Prune)
+	if err != nil {
+		return nil, err
+	}
+	summary.Print(os.Stderr)
+	return reconciled, nil
+}
 
-		case "yaml":
-			bs, err := yaml.Marshal(stories)
-			if err != nil {
-				return err
-			}
-			fmt.Print(string(bs))
+// checkReconcileFlags validates --reconcile and --prune against the other
+// flags, as reconciliation requires a complete crawl.
+func checkReconcileFlags() error {
+	switch {
+	case Prune && !Reconcile:
+		return errors.New("--prune requires --reconcile")
+	case Reconcile && ReadExisting == "":
+		return errors.New("--reconcile requires -e/--existing")
+	case Reconcile && MaxStories > 0:
+		return errors.New("--reconcile requires crawling the entire listing, -m can't be used with it")
+	case Reconcile && Since != "":
+		return errors.New("--reconcile requires crawling the entire listing, --since can't be used with it")
+	case Reconcile && Section == "comments":
+		return errors.New("--reconcile isn't supported for the comments section")
+	}
+	return nil
+}
 
-		default:
-			return fmt.Errorf("unrecognized output format %q", OutputFormat)
+// crawlComments collects the user's comment history, merging it into the
+// existing comments.
+func crawlComments(ctx context.Context, crawler *common.Crawler, existing domain.Database) (domain.Database, error) {
+	crawler.Known = common.KnownCommentIDs(existing.Comments)
+	crawler.StopAfterKnown = KnownRun
+
+	comments, _, err := crawler.UserComments(ctx)
+	if err != nil {
+		if !common.IsInterrupted(err) && !errors.Is(err, common.ErrEmptyPage) {
+			return domain.Database{}, err
 		}
+		log.Warnf("Crawl incomplete (%s), keeping the %v comments collected so far", err, len(comments))
+	}
+	comments = common.MergeUserComments(comments, existing.Comments)
 
-		return nil
-	},
+	db := domain.NewDatabase(existing.Stories)
+	db.Comments = comments
+	return db, nil
 }
 
-func getClient() (*http.Client, error) {
-	if Password == "" {
-		jar, err := cookiejar.New(nil)
-		if err != nil {
-			return nil, fmt.Errorf("creating cookie jar: %s", err)
-		}
-		client := &http.Client{
-			Jar: jar,
-			CheckRedirect: func(_ *http.Request, _ []*http.Request) error {
-				return http.ErrUseLastResponse
-			},
-		}
-		return client, nil
+// newCrawler returns a crawler for the listing at startURL, logged in when a
+// password was supplied or a saved session exists.
+func newCrawler(ctx context.Context, startURL string) (*common.Crawler, error) {
+	crawler := &common.Crawler{
+		URL:      startURL,
+		MaxItems: MaxStories,
 	}
 
-	client, err := common.Login(User, Password)
-	return client, err
+	var store *common.SessionStore
+	if SessionFile != "" {
+		store = &common.SessionStore{Path: SessionFile}
+	}
+
+	session, err := common.OpenSession(ctx, store, User, Password)
+	if err == nil {
+		crawler.Session = session
+		return crawler, nil
+	} else if err != common.ErrNoCredentials {
+		return nil, err
+	}
+
+	// Require a password or saved session when collecting user upvotes.
+	if Section == "upvotes" {
+		return nil, errors.New("Missing required flag: -p/--password must not be empty when there is no saved session")
+	}
+
+	jar, err := cookiejar.New(nil)
+	if err != nil {
+		return nil, fmt.Errorf("creating cookie jar: %s", err)
+	}
+	crawler.Client = common.NoAuthClient()
+	crawler.Client.Jar = jar
+	return crawler, nil
 }
@@ -0,0 +1,128 @@
+package main
+
+import (
+	"fmt"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/jaytaylor/hn-utils/common"
+)
+
+// Param is a typed parameter of a section's path.
+type Param string
+
+const (
+	UserParam Param = "user" // HN username, from -i/--id.
+	DateParam Param = "date" // Day in YYYY-MM-DD form, from --date.
+	SiteParam Param = "site" // Domain name, from --site.
+)
+
+// Flag returns the command-line flag supplying the value of p.
+func (p Param) Flag() string {
+	switch p {
+	case UserParam:
+		return "-i/--id"
+	case DateParam:
+		return "--date"
+	case SiteParam:
+		return "--site"
+	}
+	return string(p)
+}
+
+// paramValues returns the value of each Param from the command-line flags.
+func paramValues() map[Param]string {
+	return map[Param]string{
+		UserParam: ID,
+		DateParam: Date,
+		SiteParam: Site,
+	}
+}
+
+// Validate checks that v is a well-formed value for p.
+func (p Param) Validate(v string) error {
+	switch p {
+	case DateParam:
+		if _, err := time.Parse(common.FrontDayLayout, v); err != nil {
+			return fmt.Errorf("invalid %v %q: must be a date in YYYY-MM-DD form", p.Flag(), v)
+		}
+	case SiteParam:
+		if strings.ContainsAny(v, "/:?# ") {
+			return fmt.Errorf("invalid %v %q: must be a bare domain name, e.g. github.com", p.Flag(), v)
+		}
+	}
+	return nil
+}
+
+// Listing is a paginated HN listing, selected via -s/--section.
+type Listing struct {
+	Path   string  // Path with a "{param}" placeholder for each of Params.
+	Params []Param // Parameters required to build the path.
+
+	// Chronological listings are ordered newest first by submission time, so
+	// --since can stop at the first older item.  Others (e.g. favorites, in
+	// order of favoriting) may list newer items after older ones.
+	Chronological bool
+}
+
+// URL returns the full URL of the named section, filling in its parameters
+// from values (see paramValues).
+func (s Listing) URL(name string, values map[Param]string) (string, error) {
+	path := s.Path
+	for _, p := range s.Params {
+		v := values[p]
+		if v == "" {
+			return "", fmt.Errorf("Missing required flag: %v must not be empty for section=%v", p.Flag(), name)
+		}
+		if err := p.Validate(v); err != nil {
+			return "", err
+		}
+		path = strings.Replace(path, "{"+string(p)+"}", url.QueryEscape(v), -1)
+	}
+	return common.BaseURL + path, nil
+}
+
+// TODO: Add "story", but will require updates to support threaded structure.
+var Sections = map[string]Listing{
+	"active":      {Path: "/active"},
+	"ask":         {Path: "/ask"},
+	"asknew":      {Path: "/asknew", Chronological: true},
+	"best":        {Path: "/best"},
+	"comments":    {Path: "/threads?id={user}", Params: []Param{UserParam}, Chronological: true},
+	"favorites":   {Path: "/favorites?id={user}", Params: []Param{UserParam}},
+	"front":       {Path: "/front?day={date}", Params: []Param{DateParam}},
+	"frontpage":   {Path: "/"},
+	"jobs":        {Path: "/jobs"},
+	"launches":    {Path: "/launches"},
+	"new":         {Path: "/newest", Chronological: true},
+	"noob":        {Path: "/noobstories", Chronological: true},
+	"show":        {Path: "/show"},
+	"shownew":     {Path: "/shownew", Chronological: true},
+	"site":        {Path: "/from?site={site}", Params: []Param{SiteParam}, Chronological: true},
+	"submissions": {Path: "/submitted?id={user}", Params: []Param{UserParam}, Chronological: true},
+	"upvotes":     {Path: "/upvoted?id={user}", Params: []Param{UserParam}},
+}
+
+// sectionNames returns the sorted names of all sections.
+func sectionNames() []string {
+	names := []string{}
+	for name := range Sections {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// chronologicalSectionNames returns the sorted names of the sections listing
+// items newest first.
+func chronologicalSectionNames() []string {
+	names := []string{}
+	for _, name := range sectionNames() {
+		if Sections[name].Chronological {
+			names = append(names, name)
+		}
+	}
+	return names
+}
@@ -0,0 +1,85 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/jaytaylor/hn-utils/common"
+)
+
+func TestListingURL(t *testing.T) {
+	testCases := []struct {
+		section     string
+		values      map[Param]string
+		expected    string
+		expectedErr string
+	}{
+		{
+			section:  "frontpage",
+			expected: common.BaseURL + "/",
+		},
+		{
+			section:  "favorites",
+			values:   map[Param]string{UserParam: "jaytaylor"},
+			expected: common.BaseURL + "/favorites?id=jaytaylor",
+		},
+		{
+			section:     "favorites",
+			values:      map[Param]string{DateParam: "2019-01-16"},
+			expectedErr: "Missing required flag: -i/--id must not be empty for section=favorites",
+		},
+		{
+			section:     "front",
+			expectedErr: "Missing required flag: --date must not be empty for section=front",
+		},
+		{
+			section:     "front",
+			values:      map[Param]string{DateParam: "16/01/2019"},
+			expectedErr: `invalid --date "16/01/2019": must be a date in YYYY-MM-DD form`,
+		},
+		{
+			section:  "front",
+			values:   map[Param]string{DateParam: "2019-01-16"},
+			expected: common.BaseURL + "/front?day=2019-01-16",
+		},
+		{
+			section:     "site",
+			values:      map[Param]string{SiteParam: "github.com/jaytaylor"},
+			expectedErr: `invalid --site "github.com/jaytaylor": must be a bare domain name, e.g. github.com`,
+		},
+		{
+			section:  "site",
+			values:   map[Param]string{SiteParam: "github.com"},
+			expected: common.BaseURL + "/from?site=github.com",
+		},
+		{
+			section:  "submissions",
+			values:   map[Param]string{UserParam: "a&b=c d"},
+			expected: common.BaseURL + "/submitted?id=a%26b%3Dc+d",
+		},
+	}
+
+	for i, testCase := range testCases {
+		actual, err := Sections[testCase.section].URL(testCase.section, testCase.values)
+		if testCase.expectedErr != "" {
+			if err == nil || err.Error() != testCase.expectedErr {
+				t.Errorf("[i=%v] Expected err=%q but actual=%v", i, testCase.expectedErr, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("[i=%v] %s", i, err)
+			continue
+		}
+		if expected := testCase.expected; actual != expected {
+			t.Errorf("[i=%v] Expected URL=%q but actual=%q", i, expected, actual)
+		}
+	}
+}
+
+func TestChronologicalSectionNames(t *testing.T) {
+	expected := "asknew, comments, new, noob, shownew, site, submissions"
+	if actual := strings.Join(chronologicalSectionNames(), ", "); actual != expected {
+		t.Errorf("Expected chronological sections=%q but actual=%q", expected, actual)
+	}
+}
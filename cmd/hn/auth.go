@@ -0,0 +1,119 @@
+package main
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/jaytaylor/hn-utils/common"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+func init() {
+	authCmd.AddCommand(
+		authLoginCmd,
+		authStatusCmd,
+		authLogoutCmd,
+	)
+}
+
+var authCmd = &cobra.Command{
+	Use:   "auth",
+	Short: "Manages the saved HN login session",
+	Long:  "Manages the HN session cookies saved in --session-file, which let subsequent runs skip logging in",
+}
+
+var authLoginCmd = &cobra.Command{
+	Use:   "login",
+	Short: "Logs in and saves the session",
+	Run: func(cmd *cobra.Command, _ []string) {
+		store := sessionStore()
+		if store == nil {
+			log.Fatal("--session-file must not be empty")
+		}
+
+		session := &common.Session{
+			Client:   common.NoAuthClient(),
+			BaseURL:  common.BaseURL,
+			Username: User,
+			Password: Password,
+			Store:    store,
+		}
+		if err := session.Login(cmd.Context()); err != nil {
+			log.Fatal(err)
+		}
+		fmt.Printf("Logged in as %v, session saved to %v\n", User, store.Path)
+	},
+}
+
+var authStatusCmd = &cobra.Command{
+	Use:   "status",
+	Short: "Checks whether the saved session is still valid",
+	Run: func(cmd *cobra.Command, _ []string) {
+		store := sessionStore()
+		if store == nil {
+			log.Fatal("--session-file must not be empty")
+		}
+
+		session := &common.Session{
+			Client:   common.NoAuthClient(),
+			BaseURL:  common.BaseURL,
+			Username: User,
+			Store:    store,
+		}
+		restored, err := session.Restore()
+		if err != nil {
+			log.Fatal(err)
+		}
+		if !restored {
+			fmt.Printf("No saved session for %v in %v\n", User, store.Path)
+			return
+		}
+
+		doc, err := common.GetDocument(cmd.Context(), session.Client, common.BaseURL+"/news")
+		if err != nil {
+			log.Fatal(err)
+		}
+		if !common.IsLoggedIn(doc) {
+			fmt.Printf("Saved session for %v is no longer valid; run 'hn auth login' to renew it\n", User)
+			return
+		}
+		fmt.Printf("Logged in as %v (session saved in %v)\n", User, store.Path)
+	},
+}
+
+var authLogoutCmd = &cobra.Command{
+	Use:   "logout",
+	Short: "Deletes the saved session",
+	Run: func(_ *cobra.Command, _ []string) {
+		store := sessionStore()
+		if store == nil {
+			log.Fatal("--session-file must not be empty")
+		}
+
+		if err := store.Delete(User); err != nil {
+			log.Fatal(err)
+		}
+		fmt.Printf("Deleted saved session for %v\n", User)
+	},
+}
+
+// sessionStore returns the session store selected by --session-file, or nil
+// if session saving was disabled.
+func sessionStore() *common.SessionStore {
+	if SessionFile == "" {
+		return nil
+	}
+	return &common.SessionStore{Path: SessionFile}
+}
+
+// openSession returns a session for --user, reusing a saved one when possible.
+// common.ErrNoCredentials is returned when there is neither a password nor a
+// saved session.
+func openSession(ctx context.Context) (*common.Session, error) {
+	if User == "" {
+		return nil, common.ErrNoCredentials
+	}
+	return common.OpenSession(ctx, sessionStore(), User, Password)
+}
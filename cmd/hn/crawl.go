@@ -0,0 +1,114 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/jaytaylor/hn-utils/common"
+	"github.com/jaytaylor/hn-utils/domain"
+
+	"github.com/PuerkitoBio/goquery"
+	log "github.com/sirupsen/logrus"
+	"gopkg.in/yaml.v2"
+)
+
+// crawlStories collects a story listing starting at the specified URL and
+// merges it into any pre-existing stories, reconciling the two when requested.
+// An interrupted crawl keeps whatever was collected before the interruption.
+//
+// A nil session means crawling anonymously.
+func crawlStories(ctx context.Context, session *common.Session, startURL string) domain.Stories {
+	var (
+		existingStories domain.Stories
+		crawler         = &common.Crawler{
+			Client:   common.NoAuthClient(),
+			Session:  session,
+			URL:      startURL,
+			MaxItems: MaxItems,
+			Since:    since,
+		}
+	)
+
+	if ReadExisting != "" {
+		var err error
+		if existingStories, err = common.LoadStories(ReadExisting); err != nil {
+			log.Fatal(err)
+		}
+		if !Reconcile {
+			crawler.Known = common.KnownIDs(existingStories)
+			crawler.StopAfterKnown = KnownRun
+		}
+	}
+
+	stories, _, err := crawler.Stories(ctx)
+	if err != nil {
+		if !common.IsInterrupted(err) && !errors.Is(err, common.ErrEmptyPage) {
+			log.Fatal(err)
+		}
+		log.Warnf("Crawl incomplete (%s), keeping the %v stories collected so far", err, len(stories))
+	}
+	if WithComments && err == nil {
+		common.FetchDiscussionsLogged(ctx, crawler.GetDocument, stories, Workers, commentStates)
+	}
+	return mergeOrReconcile(stories, existingStories, err == nil)
+}
+
+// mergeOrReconcile merges the crawled stories into the existing ones, or with
+// --reconcile reconciles the two and prints the summary of changes to STDERR.
+// An incomplete crawl is merged with a warning instead of being reconciled.
+func mergeOrReconcile(stories domain.Stories, existing domain.Stories, complete bool) domain.Stories {
+	if !Reconcile {
+		return common.MergeStories(stories, existing)
+	}
+	if !complete {
+		log.Warn("Crawl incomplete, skipping reconciliation")
+		return common.MergeStories(stories, existing)
+	}
+
+	reconciled, summary, err := common.ReconcileStories(stories, existing, time.Now().UTC(), Prune)
+	if err != nil {
+		log.Fatal(err)
+	}
+	summary.Print(os.Stderr)
+	return reconciled
+}
+
+// getDocument retrieves and parses the specified page, through the session
+// when there is one.
+func getDocument(ctx context.Context, session *common.Session, page string) (*goquery.Document, error) {
+	if session != nil {
+		return session.GetDocument(ctx, page)
+	}
+	return common.GetDocument(ctx, common.NoAuthClient(), page)
+}
+
+// emitStories prints stories, wrapped in a versioned domain.Database.
+func emitStories(stories domain.Stories) {
+	emit(domain.NewDatabase(stories))
+}
+
+// emit prints v to STDOUT in the selected output format.
+func emit(v interface{}) {
+	switch OutputFormat {
+	case "json":
+		bs, err := json.MarshalIndent(v, "", "    ")
+		if err != nil {
+			log.Fatal(err)
+		}
+		fmt.Print(string(bs))
+
+	case "yaml":
+		bs, err := yaml.Marshal(v)
+		if err != nil {
+			log.Fatal(err)
+		}
+		fmt.Print(string(bs))
+
+	default:
+		log.Fatalf("unrecognized output format %q", OutputFormat)
+	}
+}
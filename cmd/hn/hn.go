@@ -1,6 +1,10 @@
 package main
 
 import (
+	"context"
+	"errors"
+	"time"
+
 	"github.com/jaytaylor/hn-utils/common"
 	log "github.com/sirupsen/logrus"
 
@@ -17,6 +21,26 @@ var (
 	OutputFormat string
 	MaxItems     int
 	ReadExisting string
+	KnownRun     int
+	Reconcile    bool
+	Prune        bool
+	Since        string
+	MinDelay     time.Duration
+	Retries      int
+	Backoff      time.Duration
+	MaxBackoff   time.Duration
+	SessionFile  string
+	WithComments bool
+	Workers      int
+
+	CommentStates string
+
+	PasswordCommand string
+	PasswordFile    string
+	PasswordPrompt  bool
+
+	since         time.Time                 // Parsed Since.
+	commentStates common.CommentStatePolicy // Parsed CommentStates.
 )
 
 func init() {
@@ -24,19 +48,40 @@ func init() {
 	rootCmd.PersistentFlags().BoolVarP(&Verbose, "verbose", "v", false, "Activate verbose log output")
 
 	rootCmd.PersistentFlags().StringVarP(&User, "user", "u", defaultUser, "HN username to authenticate with")
-	rootCmd.PersistentFlags().StringVarP(&Password, "password", "p", "", "HN account password")
+	rootCmd.PersistentFlags().StringVarP(&Password, "password", "p", "", "HN account password (visible in shell history and process listings, prefer one of the alternatives below)")
+	rootCmd.PersistentFlags().StringVarP(&PasswordCommand, "password-command", "", "", "Credential helper command whose output is the HN password (the username is passed via $HN_USER)")
+	rootCmd.PersistentFlags().StringVarP(&PasswordFile, "password-file", "", "", "File containing the HN password")
+	rootCmd.PersistentFlags().BoolVarP(&PasswordPrompt, "password-prompt", "", false, "Prompt for the HN password on the terminal; password sources in order of precedence are: --password, --password-command, --password-file, $"+common.PasswordEnvVar+", --password-prompt")
 	rootCmd.PersistentFlags().StringVarP(&OutputFormat, "output", "o", "json", `Output format, one of: "json", "yaml"`)
 	rootCmd.PersistentFlags().IntVarP(&MaxItems, "max", "m", -1, "Maximum number of items to collect (when applicable)")
 	rootCmd.PersistentFlags().StringVarP(&ReadExisting, "existing", "e", "", `Load an existing array of items from named JSON database file and front-load new content (set to "-" to read from STDIN)`)
+	rootCmd.PersistentFlags().IntVarP(&KnownRun, "stop-after-known", "", common.DefaultStopAfterKnown, "With --existing, stop once this many consecutive items are already in the database (known items seen along the way have their points and comment counts refreshed)")
+	rootCmd.PersistentFlags().BoolVarP(&Reconcile, "reconcile", "", false, "With --existing, crawl the entire listing and mark stories no longer in it (e.g. unfavorited) with a removed_at time")
+	rootCmd.PersistentFlags().BoolVarP(&Prune, "prune", "", false, "With --reconcile, drop stories no longer in the listing instead of marking them")
+	rootCmd.PersistentFlags().StringVarP(&Since, "since", "", "", "Stop upon reaching a story submitted before this day (YYYY-MM-DD) or time (RFC 3339), for story listings; favorites and upvoted are ordered by when stories were favorited or upvoted, so newer stories listed after an older one are missed")
+	rootCmd.PersistentFlags().DurationVarP(&MinDelay, "delay", "", 500*time.Millisecond, "Minimum delay between HTTP requests")
+	rootCmd.PersistentFlags().IntVarP(&Retries, "retries", "", 5, "Maximum number of retries for rate-limited or failed HTTP requests")
+	rootCmd.PersistentFlags().DurationVarP(&Backoff, "backoff", "", 2*time.Second, "Initial retry backoff delay, doubled after each failed attempt")
+	rootCmd.PersistentFlags().DurationVarP(&MaxBackoff, "max-backoff", "", time.Minute, "Maximum retry backoff delay")
+	rootCmd.PersistentFlags().BoolVarP(&WithComments, "with-comments", "", false, "Also fetch the full discussion of each story in a listing")
+	rootCmd.PersistentFlags().IntVarP(&Workers, "workers", "", common.DefaultDiscussionWorkers, "Number of discussions to fetch concurrently with --with-comments (requests remain subject to --delay)")
+	rootCmd.PersistentFlags().StringVarP(&CommentStates, "comment-states", "", "mark", `What to do with dead, flagged and deleted comments in discussions, one of: "mark" (keep with status fields set), "include" (keep as shown, placeholder text included), "drop"`)
+	rootCmd.PersistentFlags().StringVarP(&SessionFile, "session-file", "", common.DefaultSessionStorePath(), "File to save login sessions in for reuse by later runs (set to empty string to disable)")
 
 	rootCmd.AddCommand(
+		authCmd,
 		favoritesCmd,
+		itemsCmd,
 		upvotedCmd,
+		userCmd,
 	)
 }
 
 func main() {
-	if err := rootCmd.Execute(); err != nil {
+	ctx, cancel := common.InterruptContext(context.Background())
+	defer cancel()
+
+	if err := rootCmd.ExecuteContext(ctx); err != nil {
 		log.Fatal(err)
 	}
 }
@@ -45,7 +90,53 @@ var rootCmd = &cobra.Command{
 	Use:   "hn",
 	Short: "HN data retrieval tools",
 	Long:  "Tools for retrieving data from HackerNews (news.ycombinator.com) via scraping",
-	PersistentPreRun: func(_ *cobra.Command, _ []string) {
+	PersistentPreRun: func(cmd *cobra.Command, _ []string) {
 		common.InitLogging(Quiet, Verbose)
+
+		passwords := common.PasswordSources{
+			Flag:    Password,
+			Command: PasswordCommand,
+			File:    PasswordFile,
+			Prompt:  PasswordPrompt,
+		}
+		var err error
+		if Password, err = passwords.Resolve(cmd.Context(), User); err != nil {
+			log.Fatal(err)
+		}
+
+		if err := checkReconcileFlags(); err != nil {
+			log.Fatal(err)
+		}
+		if Since != "" {
+			if since, err = common.ParseTime(Since); err != nil {
+				log.Fatalf("Invalid --since: %s", err)
+			}
+		}
+		if commentStates, err = common.ParseCommentStatePolicy(CommentStates); err != nil {
+			log.Fatal(err)
+		}
+
+		common.Transport = &common.Throttle{
+			MinInterval: MinDelay,
+			MaxRetries:  Retries,
+			Backoff:     Backoff,
+			MaxBackoff:  MaxBackoff,
+		}
 	},
 }
+
+// checkReconcileFlags validates --reconcile and --prune against the other
+// flags, as reconciliation requires a complete crawl.
+func checkReconcileFlags() error {
+	switch {
+	case Prune && !Reconcile:
+		return errors.New("--prune requires --reconcile")
+	case Reconcile && ReadExisting == "":
+		return errors.New("--reconcile requires -e/--existing")
+	case Reconcile && MaxItems > 0:
+		return errors.New("--reconcile requires crawling the entire listing, -m can't be used with it")
+	case Reconcile && Since != "":
+		return errors.New("--reconcile requires crawling the entire listing, --since can't be used with it")
+	}
+	return nil
+}
@@ -1,9 +1,12 @@
 package main
 
 import (
-	"encoding/json"
+	"bufio"
+	"context"
 	"fmt"
-	"strings"
+	"io"
+	"os"
+	"strconv"
 
 	"github.com/jaytaylor/hn-utils/common"
 	"github.com/jaytaylor/hn-utils/domain"
@@ -11,108 +14,75 @@ import (
 	"github.com/PuerkitoBio/goquery"
 	log "github.com/sirupsen/logrus"
 	"github.com/spf13/cobra"
-	"gopkg.in/yaml.v2"
 )
 
 var itemsCmd = &cobra.Command{
-	Use:   "items",
+	Use:   "items [id]...",
 	Short: "Downloads HN items by ID",
-	Long:  "Retrieves items by ID and emit as an array of structured objects; providing a login/password lets HN know who you are so they hopefully don't blacklist you",
-	PreRun: func(_ *cobra.Command, _ []string) {
-		if Password == "" {
-			log.Warnf("-p/--password flag is absent; there is an increased change this client will be blacklisted")
-		}
-	},
+	Long:  "Retrieves items by ID (from the arguments, or whitespace-separated on STDIN) along with their full discussions and emits them as an array of structured Story objects; providing a login/password lets HN know who you are so they hopefully don't blacklist you",
 	Run: func(cmd *cobra.Command, args []string) {
-		var (
-			moreLink        = fmt.Sprintf("%v/item?id=%v", common.BaseURL, User)
-			stories         = domain.Stories{}
-			existingStories domain.Stories
-			existingID      int64 = -1 // Used for picking up where an existing collection ends.
-		)
-
-		if ReadExisting != "" {
-			var err error
-			if existingStories, err = common.LoadStories(ReadExisting); err != nil {
-				log.Fatal(err)
-			} else if len(existingStories) > 0 {
-				existingID = existingStories[0].ID
-			}
+		ids, err := itemIDs(args, os.Stdin)
+		if err != nil {
+			log.Fatal(err)
 		}
 
-		client, err := common.Login(User, Password)
-		if err != nil {
+		session, err := openSession(cmd.Context())
+		if err == common.ErrNoCredentials {
+			log.Warnf("-p/--password flag is absent and there is no saved session; there is an increased change this client will be blacklisted")
+		} else if err != nil {
 			log.Fatal(err)
 		}
-		log.Debug("Logged in successfully")
 
-		for len(moreLink) > 0 {
-			log.WithField("more-link", moreLink).Debug("Fetching")
+		get := func(ctx context.Context, page string) (*goquery.Document, error) {
+			return getDocument(ctx, session, page)
+		}
 
-			rc, err := common.CheckedGet(client, moreLink)
-			if err != nil {
-				log.Fatal(err)
+		stories := domain.Stories{}
+		for _, id := range ids {
+			discussion := common.NewDiscussion()
+			discussion.Lenient = true
+			discussion.States = commentStates
+			story, err := discussion.Fetch(cmd.Context(), get, fmt.Sprintf("%v/item?id=%v", common.BaseURL, id))
+			for _, warning := range discussion.Warnings {
+				log.WithField("item-id", id).Warnf("Discussion parse: %s", warning)
 			}
-
-			doc, err := goquery.NewDocumentFromReader(rc)
 			if err != nil {
-				log.Fatal(err)
-			}
-			if err := rc.Close(); err != nil {
-				log.Fatalf("closing response body from %v: %s", moreLink, err)
-			}
-
-			var caughtUp bool
-
-			doc.Find(".athing").EachWithBreak(func(i int, s *goquery.Selection) bool {
-				if caughtUp {
-					return false
-				}
-
-				story := common.ExtractStory(s)
-
-				if story.ID == existingID {
-					log.WithField("story-id", story.ID).Debug("Caught up to newest story in pre-existing data")
-					caughtUp = true
-					stories = append(stories, existingStories...)
+				if !common.IsInterrupted(err) {
+					log.Fatal(err)
 				}
-
-				stories = append(stories, story)
-
-				return true
-			})
-
-			if caughtUp {
-				break
-			}
-
-			moreLink = doc.Find(".morelink").Last().AttrOr("href", "")
-			if len(moreLink) > 0 && !strings.HasPrefix(moreLink, "https://") {
-				moreLink = fmt.Sprintf("%s/%s", common.BaseURL, moreLink)
-			}
-
-			if MaxItems != -1 && len(stories) >= MaxItems {
+				log.Warnf("Interrupted, keeping the %v items collected so far", len(stories))
 				break
 			}
+			stories = append(stories, story)
 		}
 
-		switch OutputFormat {
-		case "json":
-			bs, err := json.MarshalIndent(stories, "", "    ")
-			if err != nil {
-				log.Fatal(err)
-			}
-			fmt.Print(string(bs))
+		emitStories(stories)
+	},
+}
 
-		case "yaml":
-			bs, err := yaml.Marshal(stories)
-			if err != nil {
-				log.Fatal(err)
-			}
-			fmt.Print(string(bs))
+// itemIDs parses item IDs from args, or from r when args is empty.
+func itemIDs(args []string, r io.Reader) ([]int64, error) {
+	if len(args) == 0 {
+		scanner := bufio.NewScanner(r)
+		scanner.Split(bufio.ScanWords)
+		for scanner.Scan() {
+			args = append(args, scanner.Text())
+		}
+		if err := scanner.Err(); err != nil {
+			return nil, fmt.Errorf("reading item IDs from STDIN: %s", err)
+		}
+	}
 
-		default:
-			log.Fatalf("unrecognized output format %q", OutputFormat)
+	ids := make([]int64, 0, len(args))
+	for _, arg := range args {
+		id, err := strconv.ParseInt(arg, 10, 64)
+		if err != nil || id < 1 {
+			return nil, fmt.Errorf("invalid item ID %q", arg)
 		}
-	},
+		ids = append(ids, id)
+	}
+	if len(ids) == 0 {
+		return nil, fmt.Errorf("no item IDs provided")
+	}
+	return ids, nil
 }
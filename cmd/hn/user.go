@@ -0,0 +1,54 @@
+package main
+
+import (
+	"fmt"
+	"net/url"
+	"time"
+
+	"github.com/jaytaylor/hn-utils/common"
+	"github.com/jaytaylor/hn-utils/domain"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+var KarmaLog string
+
+func init() {
+	userCmd.Flags().StringVarP(&KarmaLog, "karma-log", "", "", "Also append a timestamped karma snapshot to the named file of newline-delimited JSON objects, for charting karma over time")
+}
+
+var userCmd = &cobra.Command{
+	Use:   "user [name]",
+	Short: "Downloads an HN user profile",
+	Long:  "Retrieves the profile of the named HN user (created date, karma, about text and links to their submissions, comments and favorites) as a structured User object",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		session, err := openSession(cmd.Context())
+		if err != nil && err != common.ErrNoCredentials {
+			log.Fatal(err)
+		}
+
+		doc, err := getDocument(cmd.Context(), session, fmt.Sprintf("%v/user?id=%v", common.BaseURL, url.QueryEscape(args[0])))
+		if err != nil {
+			log.Fatal(err)
+		}
+		user, err := common.ExtractUser(doc.Selection, common.BaseURL)
+		if err != nil {
+			log.Fatalf("%s: %v", err, args[0])
+		}
+
+		if KarmaLog != "" {
+			snapshot := domain.KarmaSnapshot{
+				User:      user.ID,
+				Karma:     user.Karma,
+				Timestamp: time.Now().UTC(),
+			}
+			if err := common.AppendKarmaSnapshot(KarmaLog, snapshot); err != nil {
+				log.Fatal(err)
+			}
+		}
+
+		emit(user)
+	},
+}
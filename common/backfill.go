@@ -0,0 +1,135 @@
+package common
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"time"
+
+	"github.com/jaytaylor/hn-utils/domain"
+)
+
+// FrontDayLayout is the format of the days of historical front pages.
+const FrontDayLayout = "2006-01-02"
+
+// Backfill collects the historical front pages ("/front?day=YYYY-MM-DD") of a
+// range of days, tagging each story with the day and rank it appeared at.
+//
+// Progress is saved to Checkpoint after each day, so an interrupted backfill
+// resumes with the next day when run again with the same range.
+type Backfill struct {
+	Crawler    *Crawler  // Used for all requests.  Its URL is set for each day, and MaxItems applies per day.
+	From       time.Time // First day.
+	To         time.Time // Last day (inclusive).
+	Checkpoint string    // Path of the checkpoint file.  Empty disables checkpointing.
+}
+
+// BackfillCheckpoint is the saved progress of a Backfill.
+type BackfillCheckpoint struct {
+	From    string         `json:"from"`
+	To      string         `json:"to"`
+	Done    string         `json:"done,omitempty"` // Last day collected in full.
+	Stories domain.Stories `json:"stories"`        // Stories of the days collected so far, newest day first.
+}
+
+// Run collects the front pages from the day after the last checkpointed one
+// through To.  On error, the stories of the days collected in full so far are
+// returned alongside it.
+func (b *Backfill) Run(ctx context.Context) (domain.Stories, error) {
+	if b.To.Before(b.From) {
+		return nil, fmt.Errorf("backfill range ends (%v) before it begins (%v)", b.To.Format(FrontDayLayout), b.From.Format(FrontDayLayout))
+	}
+
+	checkpoint, err := b.loadCheckpoint()
+	if err != nil {
+		return nil, err
+	}
+
+	day := b.From
+	if checkpoint.Done != "" {
+		done, err := time.Parse(FrontDayLayout, checkpoint.Done)
+		if err != nil {
+			return nil, fmt.Errorf("parsing checkpoint %v: %s", b.Checkpoint, err)
+		}
+		day = done.AddDate(0, 0, 1)
+		b.Crawler.logger().Infof("Resuming backfill after %v", checkpoint.Done)
+	}
+
+	for ; !day.After(b.To); day = day.AddDate(0, 0, 1) {
+		tag := day.Format(FrontDayLayout)
+		b.Crawler.URL = fmt.Sprintf("%v/front?day=%v", BaseURL, tag)
+
+		stories, _, err := b.Crawler.Stories(ctx)
+		if err != nil {
+			return checkpoint.Stories, fmt.Errorf("collecting front page of %v: %w", tag, err)
+		}
+		for i := range stories {
+			stories[i].FrontDay = tag
+		}
+		b.Crawler.logger().WithField("day", tag).Debugf("Collected %v stories", len(stories))
+
+		checkpoint.Stories = append(stories, checkpoint.Stories...)
+		checkpoint.Done = tag
+		if err := b.saveCheckpoint(checkpoint); err != nil {
+			return checkpoint.Stories, err
+		}
+	}
+
+	return checkpoint.Stories, nil
+}
+
+// loadCheckpoint returns the saved progress for the range, or a fresh
+// checkpoint when there is none.
+func (b *Backfill) loadCheckpoint() (*BackfillCheckpoint, error) {
+	fresh := &BackfillCheckpoint{
+		From:    b.From.Format(FrontDayLayout),
+		To:      b.To.Format(FrontDayLayout),
+		Stories: domain.Stories{},
+	}
+	if b.Checkpoint == "" {
+		return fresh, nil
+	}
+
+	bs, err := ioutil.ReadFile(b.Checkpoint)
+	if os.IsNotExist(err) {
+		return fresh, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("reading checkpoint: %s", err)
+	}
+
+	var checkpoint BackfillCheckpoint
+	if err := json.Unmarshal(bs, &checkpoint); err != nil {
+		return nil, fmt.Errorf("parsing checkpoint %v: %s", b.Checkpoint, err)
+	}
+	if checkpoint.From != fresh.From || checkpoint.To != fresh.To {
+		return nil, fmt.Errorf("checkpoint %v is for the range %v to %v, not %v to %v", b.Checkpoint, checkpoint.From, checkpoint.To, fresh.From, fresh.To)
+	}
+	if checkpoint.Stories == nil {
+		checkpoint.Stories = domain.Stories{}
+	}
+	return &checkpoint, nil
+}
+
+func (b *Backfill) saveCheckpoint(checkpoint *BackfillCheckpoint) error {
+	if b.Checkpoint == "" {
+		return nil
+	}
+
+	bs, err := json.Marshal(checkpoint)
+	if err != nil {
+		return fmt.Errorf("encoding checkpoint: %s", err)
+	}
+
+	// Write to a temporary file first so an interruption can't leave a
+	// truncated checkpoint behind.
+	tmp := b.Checkpoint + ".tmp"
+	if err := ioutil.WriteFile(tmp, bs, 0644); err != nil {
+		return fmt.Errorf("writing checkpoint: %s", err)
+	}
+	if err := os.Rename(tmp, b.Checkpoint); err != nil {
+		return fmt.Errorf("writing checkpoint: %s", err)
+	}
+	return nil
+}
@@ -0,0 +1,84 @@
+package common
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestBackfill(t *testing.T) {
+	var (
+		requests = map[string]int{}
+		failDay  = "2019-01-03"
+	)
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/front", func(w http.ResponseWriter, req *http.Request) {
+		day := req.URL.Query().Get("day")
+		requests[day]++
+		if day == failDay {
+			http.Error(w, "boom", http.StatusInternalServerError)
+			return
+		}
+		var base int64
+		fmt.Sscanf(day, "2019-01-%d", &base)
+		fmt.Fprint(w, listingPage("", base*10+1, base*10+2))
+	})
+	server := httptest.NewServer(mux)
+	origBaseURL := BaseURL
+	BaseURL = server.URL
+	defer func() {
+		BaseURL = origBaseURL
+		server.Close()
+	}()
+
+	backfill := &Backfill{
+		Crawler:    &Crawler{Client: server.Client()},
+		From:       time.Date(2019, 1, 1, 0, 0, 0, 0, time.UTC),
+		To:         time.Date(2019, 1, 4, 0, 0, 0, 0, time.UTC),
+		Checkpoint: filepath.Join(t.TempDir(), "checkpoint.json"),
+	}
+
+	// The first run fails on the third day.
+	stories, err := backfill.Run(context.Background())
+	if err == nil {
+		t.Fatal("Expected error but got none")
+	}
+	if expected, actual := 4, len(stories); actual != expected {
+		t.Fatalf("Expected len(stories)=%v but actual=%v", expected, actual)
+	}
+
+	// The second run resumes with the third day.
+	failDay = ""
+	stories, err = backfill.Run(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected, actual := 8, len(stories); actual != expected {
+		t.Fatalf("Expected len(stories)=%v but actual=%v", expected, actual)
+	}
+	if expected, actual := "2019-01-04", stories[0].FrontDay; actual != expected {
+		t.Errorf("Expected stories[0].FrontDay=%q but actual=%q", expected, actual)
+	}
+	if expected, actual := int64(11), stories[6].ID; actual != expected {
+		t.Errorf("Expected stories[6].ID=%v but actual=%v", expected, actual)
+	}
+	if expected, actual := "2019-01-01", stories[7].FrontDay; actual != expected {
+		t.Errorf("Expected stories[7].FrontDay=%q but actual=%q", expected, actual)
+	}
+	for _, day := range []string{"2019-01-01", "2019-01-02", "2019-01-04"} {
+		if expected, actual := 1, requests[day]; actual != expected {
+			t.Errorf("Expected %v requests for day=%v but actual=%v", expected, day, actual)
+		}
+	}
+
+	// A different range doesn't reuse the checkpoint.
+	backfill.To = backfill.To.AddDate(0, 0, 1)
+	if _, err := backfill.Run(context.Background()); err == nil {
+		t.Error("Expected checkpoint range mismatch error but got none")
+	}
+}
@@ -0,0 +1,164 @@
+package common
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+	"golang.org/x/net/html"
+
+	"github.com/jaytaylor/hn-utils/domain"
+)
+
+// commentTags are the elements HN allows in comment text.  Anything else is
+// unwrapped when sanitizing, i.e. only its content is kept.
+var commentTags = map[string]bool{
+	"a":      true,
+	"b":      true,
+	"br":     true,
+	"code":   true,
+	"em":     true,
+	"i":      true,
+	"p":      true,
+	"pre":    true,
+	"strong": true,
+}
+
+var (
+	markdownEscaper = strings.NewReplacer(`\`, `\\`, "*", `\*`, "_", `\_`, "`", "\\`", "[", `\[`, "]", `\]`)
+	extraNewlines   = regexp.MustCompile(`\n{3,}`)
+)
+
+// CommentHTML returns the sanitized HTML of a comment's text: only the
+// elements HN itself produces are kept, stripped of all attributes except
+// link targets, which are resolved with ReconstructHNURL.
+func CommentHTML(commtext *goquery.Selection) string {
+	var b strings.Builder
+	for _, n := range commtext.Nodes {
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			writeSanitizedHTML(&b, c)
+		}
+	}
+	return strings.TrimSpace(b.String())
+}
+
+func writeSanitizedHTML(b *strings.Builder, n *html.Node) {
+	switch n.Type {
+	case html.TextNode:
+		b.WriteString(html.EscapeString(n.Data))
+		return
+	case html.ElementNode:
+	default:
+		return
+	}
+
+	if n.Data == "script" || n.Data == "style" {
+		return
+	}
+	allowed := commentTags[n.Data]
+	if allowed {
+		b.WriteString("<" + n.Data)
+		if n.Data == "a" {
+			b.WriteString(` href="` + html.EscapeString(ReconstructHNURL(attr(n, "href"))) + `"`)
+		}
+		b.WriteString(">")
+		if n.Data == "br" {
+			return
+		}
+	}
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		writeSanitizedHTML(b, c)
+	}
+	if allowed {
+		b.WriteString("</" + n.Data + ">")
+	}
+}
+
+// CommentMarkdown renders a comment's text as Markdown, preserving code
+// blocks, emphasis, paragraphs and link targets.
+func CommentMarkdown(commtext *goquery.Selection) string {
+	var b strings.Builder
+	for _, n := range commtext.Nodes {
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			writeMarkdown(&b, c)
+		}
+	}
+	return strings.TrimSpace(extraNewlines.ReplaceAllString(b.String(), "\n\n"))
+}
+
+func writeMarkdown(b *strings.Builder, n *html.Node) {
+	switch n.Type {
+	case html.TextNode:
+		b.WriteString(markdownEscaper.Replace(n.Data))
+		return
+	case html.ElementNode:
+	default:
+		return
+	}
+
+	children := func() {
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			writeMarkdown(b, c)
+		}
+	}
+
+	switch n.Data {
+	case "p":
+		b.WriteString("\n\n")
+		children()
+		b.WriteString("\n\n")
+	case "br":
+		b.WriteString("  \n")
+	case "pre":
+		b.WriteString("\n\n```\n" + strings.TrimRight(textContent(n), "\n") + "\n```\n\n")
+	case "code":
+		b.WriteString("`" + textContent(n) + "`")
+	case "i", "em":
+		b.WriteString("*")
+		children()
+		b.WriteString("*")
+	case "b", "strong":
+		b.WriteString("**")
+		children()
+		b.WriteString("**")
+	case "a":
+		b.WriteString("[")
+		children()
+		b.WriteString("](" + ReconstructHNURL(attr(n, "href")) + ")")
+	case "script", "style":
+	default:
+		children()
+	}
+}
+
+// CommentLinks returns the outbound links in a comment's text.
+func CommentLinks(commtext *goquery.Selection) []domain.Link {
+	var links []domain.Link
+	commtext.Find("a[href]").Each(func(_ int, a *goquery.Selection) {
+		links = append(links, domain.Link{
+			URL:  ReconstructHNURL(a.AttrOr("href", "")),
+			Text: a.Text(),
+		})
+	})
+	return links
+}
+
+func attr(n *html.Node, key string) string {
+	for _, a := range n.Attr {
+		if a.Key == key {
+			return a.Val
+		}
+	}
+	return ""
+}
+
+func textContent(n *html.Node) string {
+	if n.Type == html.TextNode {
+		return n.Data
+	}
+	var b strings.Builder
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		b.WriteString(textContent(c))
+	}
+	return b.String()
+}
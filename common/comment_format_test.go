@@ -0,0 +1,48 @@
+package common
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+const formattedCommentHTML = `<span class="commtext c00">Try <i>this</i> with a_b:<p><pre><code>  for i := range xs {
+      fmt.Println(*i)
+  }
+</code></pre><p>&gt; quoted line<p>See <a href="https://example.com/a?b=1&amp;c=2" rel="nofollow">https://example.com/a?b=1&amp;c=2</a> and <a href="item?id=42">item?id=42</a>.<script>alert(1)</script><font color="red">!</font></span>`
+
+func TestCommentFormatting(t *testing.T) {
+	doc, err := goquery.NewDocumentFromReader(strings.NewReader(formattedCommentHTML))
+	if err != nil {
+		t.Fatal(err)
+	}
+	commtext := doc.Find(".commtext")
+
+	expectedHTML := `Try <i>this</i> with a_b:<p></p><pre><code>  for i := range xs {
+      fmt.Println(*i)
+  }
+</code></pre><p>&gt; quoted line</p><p>See <a href="https://example.com/a?b=1&amp;c=2">https://example.com/a?b=1&amp;c=2</a> and <a href="` + BaseURL + `/item?id=42">item?id=42</a>.!</p>`
+	if expected, actual := expectedHTML, CommentHTML(commtext); actual != expected {
+		t.Errorf("Expected html=%q but actual=%q", expected, actual)
+	}
+
+	expectedMarkdown := "Try *this* with a\\_b:\n\n```\n  for i := range xs {\n      fmt.Println(*i)\n  }\n```\n\n> quoted line\n\nSee [https://example.com/a?b=1&c=2](https://example.com/a?b=1&c=2) and [item?id=42](" + BaseURL + "/item?id=42).!"
+	if expected, actual := expectedMarkdown, CommentMarkdown(commtext); actual != expected {
+		t.Errorf("Expected markdown=%q but actual=%q", expected, actual)
+	}
+
+	links := CommentLinks(commtext)
+	if expected, actual := 2, len(links); actual != expected {
+		t.Fatalf("Expected len(links)=%v but actual=%v", expected, actual)
+	}
+	if expected, actual := "https://example.com/a?b=1&c=2", links[0].URL; actual != expected {
+		t.Errorf("Expected links[0].URL=%q but actual=%q", expected, actual)
+	}
+	if expected, actual := BaseURL+"/item?id=42", links[1].URL; actual != expected {
+		t.Errorf("Expected links[1].URL=%q but actual=%q", expected, actual)
+	}
+	if expected, actual := "item?id=42", links[1].Text; actual != expected {
+		t.Errorf("Expected links[1].Text=%q but actual=%q", expected, actual)
+	}
+}
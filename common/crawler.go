@@ -0,0 +1,297 @@
+package common
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/jaytaylor/hn-utils/domain"
+
+	"github.com/PuerkitoBio/goquery"
+	log "github.com/sirupsen/logrus"
+)
+
+// DefaultStopAfterKnown is the number of consecutive pre-existing items, a
+// listing page's worth, after which incremental crawls consider themselves
+// caught up.
+const DefaultStopAfterKnown = 30
+
+// ErrEmptyPage is returned by crawls following a "More" link to a page without
+// any items.  HN only links to pages which have some, so such a page is an
+// error page or one whose markup changed rather than the end of the listing.
+// An empty first page is taken to be an empty listing.
+var ErrEmptyPage = errors.New("no items found on page")
+
+// PageFunc is invoked once per page of a paginated listing.  Returning false
+// stops the walk.
+type PageFunc func(doc *goquery.Document) (bool, error)
+
+// GetFunc retrieves and parses a page, e.g. Session.GetDocument.
+type GetFunc func(ctx context.Context, page string) (*goquery.Document, error)
+
+// StoriesFunc is invoked with the stories extracted from each page of a story
+// listing.
+type StoriesFunc func(stories domain.Stories) error
+
+// Crawler walks a paginated HN story listing (e.g. "/news" or
+// "/favorites?id=xxx") page by page until it runs out of pages or one of the
+// configured stop conditions is met.
+type Crawler struct {
+	Client   *http.Client // Anonymous or authenticated client used for all requests.
+	Session  *Session     // Logged-in session used instead of Client when set.
+	URL      string       // Start URL of the listing.
+	MaxItems int          // Stop once this many stories are collected.  Values < 1 mean no limit.
+	UntilID  int64        // Stop upon reaching this story ID (e.g. newest pre-existing story).  Values < 1 disable.
+	Since    time.Time    // Stop upon reaching a story older than this.  Zero value disables.
+
+	// Known holds the IDs of pre-existing items.  Known items are collected
+	// like any other so that their counts can be refreshed, but the crawl
+	// stops after StopAfterKnown of them in a row.  Values < 1 disable.
+	Known          map[int64]bool
+	StopAfterKnown int
+
+	Logger log.FieldLogger // Defaults to the standard logrus logger when nil.
+}
+
+// Crawl walks the listing and hands each page of stories to fn.  Stories at or
+// beyond a stop condition are never passed to fn.
+//
+// caughtUp reports whether the crawl stopped because UntilID was reached or
+// StopAfterKnown consecutive Known stories were collected.  When ctx is
+// canceled any in-flight request is aborted, the crawl stops without handing
+// further pages to fn and ctx.Err() is returned.  A continuation page without
+// stories ends the crawl with ErrEmptyPage.
+func (c *Crawler) Crawl(ctx context.Context, fn StoriesFunc) (caughtUp bool, err error) {
+	var (
+		logger = c.logger()
+		known  = c.knownRun()
+		n      int
+		pages  int
+	)
+
+	err = walk(ctx, c.GetDocument, c.URL, func(doc *goquery.Document) (bool, error) {
+		var (
+			stories = domain.Stories{}
+			stop    bool
+		)
+		pages++
+
+		doc.Find(".athing").EachWithBreak(func(_ int, s *goquery.Selection) bool {
+			story := ExtractStory(s)
+
+			if c.UntilID > 0 && story.ID == c.UntilID {
+				logger.WithField("story-id", story.ID).Debug("Caught up to newest story in pre-existing data")
+				caughtUp = true
+				stop = true
+				return false
+			}
+			if !c.Since.IsZero() && !story.Timestamp.IsZero() && story.Timestamp.Before(c.Since) {
+				logger.WithField("story-id", story.ID).Debugf("Reached story older than %v", c.Since)
+				stop = true
+				return false
+			}
+
+			stories = append(stories, story)
+			n++
+
+			if known(story.ID) {
+				logger.WithField("story-id", story.ID).Debugf("Caught up after %v consecutive stories in pre-existing data", c.StopAfterKnown)
+				caughtUp = true
+				stop = true
+				return false
+			}
+			if c.MaxItems > 0 && n >= c.MaxItems {
+				stop = true
+				return false
+			}
+			return true
+		})
+
+		if len(stories) == 0 && !stop && pages > 1 {
+			return false, fmt.Errorf("%w: %v", ErrEmptyPage, doc.Url)
+		}
+		if len(stories) > 0 {
+			if err := fn(stories); err != nil {
+				return false, err
+			}
+		}
+		return !stop, nil
+	})
+	return
+}
+
+// Stories walks the listing and returns all collected stories.  On error, the
+// stories collected up to that point are returned alongside it.
+func (c *Crawler) Stories(ctx context.Context) (domain.Stories, bool, error) {
+	stories := domain.Stories{}
+	caughtUp, err := c.Crawl(ctx, func(page domain.Stories) error {
+		stories = append(stories, page...)
+		return nil
+	})
+	return stories, caughtUp, err
+}
+
+// UserComments walks a user's comment history ("/threads?id=xxx") and returns
+// the user's comments along with their visible replies.  The stop conditions
+// apply to the user's own comments, with UntilID and Known holding comment
+// IDs.  Parse warnings are logged to Logger.  A continuation page without
+// comments ends the walk with ErrEmptyPage.  On error,
+// the comments collected up to that point are returned alongside it.
+func (c *Crawler) UserComments(ctx context.Context) (comments []domain.UserComment, caughtUp bool, err error) {
+	var (
+		logger = c.logger()
+		known  = c.knownRun()
+		pages  int
+	)
+	comments = []domain.UserComment{}
+
+	err = walk(ctx, c.GetDocument, c.URL, func(doc *goquery.Document) (bool, error) {
+		pages++
+		page, warnings := ExtractUserComments(doc.Selection)
+		for _, warning := range warnings {
+			logger.WithField("page", doc.Url).Warnf("Comment history parse: %s", warning)
+		}
+		if len(page) == 0 {
+			if pages > 1 {
+				return false, fmt.Errorf("%w: %v", ErrEmptyPage, doc.Url)
+			}
+			return false, nil
+		}
+
+		for _, comment := range page {
+			if c.UntilID > 0 && comment.ID == c.UntilID {
+				logger.WithField("comment-id", comment.ID).Debug("Caught up to newest comment in pre-existing data")
+				caughtUp = true
+				return false, nil
+			}
+			if !c.Since.IsZero() && !comment.Timestamp.IsZero() && comment.Timestamp.Before(c.Since) {
+				logger.WithField("comment-id", comment.ID).Debugf("Reached comment older than %v", c.Since)
+				return false, nil
+			}
+
+			comments = append(comments, comment)
+
+			if known(comment.ID) {
+				logger.WithField("comment-id", comment.ID).Debugf("Caught up after %v consecutive comments in pre-existing data", c.StopAfterKnown)
+				caughtUp = true
+				return false, nil
+			}
+			if c.MaxItems > 0 && len(comments) >= c.MaxItems {
+				return false, nil
+			}
+		}
+		return true, nil
+	})
+	return
+}
+
+// GetDocument retrieves and parses the specified page through Session when
+// set, otherwise through Client.
+func (c *Crawler) GetDocument(ctx context.Context, page string) (*goquery.Document, error) {
+	if c.Session != nil {
+		return c.Session.GetDocument(ctx, page)
+	}
+	return GetDocument(ctx, c.Client, page)
+}
+
+// knownRun returns a func to be called with the ID of each collected item in
+// order, which reports whether StopAfterKnown consecutive Known items have been
+// reached.
+func (c *Crawler) knownRun() func(id int64) bool {
+	var run int
+	return func(id int64) bool {
+		if c.StopAfterKnown < 1 || !c.Known[id] {
+			run = 0
+			return false
+		}
+		run++
+		return run >= c.StopAfterKnown
+	}
+}
+
+func (c *Crawler) logger() log.FieldLogger {
+	if c.Logger == nil {
+		return log.StandardLogger()
+	}
+	return c.Logger
+}
+
+// Walk fetches each page of a paginated listing starting at page, hands the
+// parsed document to fn, and follows the ".morelink" until fn returns false or
+// there are no further pages.  Cancellation of ctx is checked between pages.
+func Walk(ctx context.Context, client *http.Client, page string, fn PageFunc) error {
+	return walk(ctx, func(ctx context.Context, page string) (*goquery.Document, error) {
+		return GetDocument(ctx, client, page)
+	}, page, fn)
+}
+
+func walk(ctx context.Context, get GetFunc, page string, fn PageFunc) error {
+	for len(page) > 0 {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		log.WithField("more-link", page).Debug("Fetching")
+
+		doc, err := get(ctx, page)
+		if err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			return err
+		}
+
+		more, err := fn(doc)
+		if err != nil {
+			return err
+		}
+		if !more {
+			break
+		}
+
+		page = NextPageURL(page, doc)
+	}
+	return nil
+}
+
+// GetDocument retrieves and parses the specified page.
+func GetDocument(ctx context.Context, client *http.Client, page string) (*goquery.Document, error) {
+	rc, err := CheckedGet(ctx, client, page)
+	if err != nil {
+		return nil, err
+	}
+
+	doc, err := goquery.NewDocumentFromReader(rc)
+	if err != nil {
+		rc.Close()
+		return nil, fmt.Errorf("parsing %v: %s", page, err)
+	}
+	if err := rc.Close(); err != nil {
+		return nil, fmt.Errorf("closing response body from %v: %s", page, err)
+	}
+	doc.Url, _ = url.Parse(page)
+	return doc, nil
+}
+
+// NextPageURL returns the full URL of the "More" link on a listing page, or an
+// empty string when there is no next page.  Relative links are resolved
+// against the URL the page was fetched from.
+func NextPageURL(page string, doc *goquery.Document) string {
+	moreLink := doc.Find(".morelink").Last().AttrOr("href", "")
+	if len(moreLink) == 0 || strings.HasPrefix(moreLink, "https://") {
+		return moreLink
+	}
+	base, err := url.Parse(page)
+	if err != nil {
+		return fmt.Sprintf("%s/%s", BaseURL, moreLink)
+	}
+	ref, err := url.Parse(moreLink)
+	if err != nil {
+		return ""
+	}
+	return base.ResolveReference(ref).String()
+}
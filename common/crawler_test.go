@@ -0,0 +1,189 @@
+package common
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/jaytaylor/hn-utils/domain"
+)
+
+// listingPage renders a minimal HN listing page with the given story IDs and
+// optional "More" link.
+func listingPage(more string, ids ...int64) string {
+	html := "<html><body><table>"
+	for _, id := range ids {
+		html += fmt.Sprintf(`<tr class="athing" id="%v"><td class="title"><a class="storylink" href="https://example.com/%v">Story %v</a></td></tr>`, id, id, id)
+		html += fmt.Sprintf(`<tr><td class="subtext"><span class="score">%v points</span> <a href="user?id=pg" class="hnuser">pg</a> <span class="age" title="2019-01-%02dT12:00:00"><a href="item?id=%v">%v days ago</a></span> <a href="item?id=%v">3 comments</a></td></tr>`, id, id, id, id, id)
+	}
+	if more != "" {
+		html += fmt.Sprintf(`<tr><td><a href="%v" class="morelink">More</a></td></tr>`, more)
+	}
+	return html + "</table></body></html>"
+}
+
+func newListingServer(t *testing.T) *httptest.Server {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/news", func(w http.ResponseWriter, req *http.Request) {
+		switch req.URL.Query().Get("p") {
+		case "":
+			fmt.Fprint(w, listingPage("news?p=2", 6, 5, 4))
+		case "2":
+			fmt.Fprint(w, listingPage("", 3, 2, 1))
+		default:
+			t.Errorf("Unexpected page request: %v", req.URL)
+		}
+	})
+	server := httptest.NewServer(mux)
+
+	origBaseURL := BaseURL
+	BaseURL = server.URL
+	t.Cleanup(func() {
+		BaseURL = origBaseURL
+		server.Close()
+	})
+	return server
+}
+
+func TestCrawler(t *testing.T) {
+	server := newListingServer(t)
+
+	testCases := []struct {
+		crawler          Crawler
+		expectedIDs      []int64
+		expectedCaughtUp bool
+	}{
+		{
+			crawler:     Crawler{},
+			expectedIDs: []int64{6, 5, 4, 3, 2, 1},
+		},
+		{
+			crawler:     Crawler{MaxItems: 4},
+			expectedIDs: []int64{6, 5, 4, 3},
+		},
+		{
+			crawler:          Crawler{UntilID: 2},
+			expectedIDs:      []int64{6, 5, 4, 3},
+			expectedCaughtUp: true,
+		},
+		{
+			crawler:          Crawler{UntilID: 5, MaxItems: -1},
+			expectedIDs:      []int64{6},
+			expectedCaughtUp: true,
+		},
+		{
+			// Stories are dated 2019-01-<ID>.
+			crawler:     Crawler{Since: time.Date(2019, 1, 4, 0, 0, 0, 0, time.UTC)},
+			expectedIDs: []int64{6, 5, 4},
+		},
+		{
+			crawler:          Crawler{Known: map[int64]bool{4: true, 3: true}, StopAfterKnown: 2},
+			expectedIDs:      []int64{6, 5, 4, 3},
+			expectedCaughtUp: true,
+		},
+		{
+			// A single known story (e.g. a re-favorited one) doesn't stop the
+			// crawl.
+			crawler:          Crawler{Known: map[int64]bool{5: true, 3: true, 2: true}, StopAfterKnown: 2},
+			expectedIDs:      []int64{6, 5, 4, 3, 2},
+			expectedCaughtUp: true,
+		},
+		{
+			crawler:     Crawler{Known: map[int64]bool{5: true, 1: true}, StopAfterKnown: 2},
+			expectedIDs: []int64{6, 5, 4, 3, 2, 1},
+		},
+		{
+			crawler:     Crawler{Known: map[int64]bool{6: true, 5: true}},
+			expectedIDs: []int64{6, 5, 4, 3, 2, 1},
+		},
+	}
+
+	for i, testCase := range testCases {
+		testCase.crawler.Client = NoAuthClient()
+		testCase.crawler.URL = server.URL + "/news"
+
+		stories, caughtUp, err := testCase.crawler.Stories(context.Background())
+		if err != nil {
+			t.Errorf("[i=%v] %s", i, err)
+			continue
+		}
+		if caughtUp != testCase.expectedCaughtUp {
+			t.Errorf("[i=%v] Expected caughtUp=%v but actual=%v", i, testCase.expectedCaughtUp, caughtUp)
+		}
+		if expected, actual := len(testCase.expectedIDs), len(stories); actual != expected {
+			t.Errorf("[i=%v] Expected len(stories)=%v but actual=%v", i, expected, actual)
+			continue
+		}
+		for j, story := range stories {
+			if expected, actual := testCase.expectedIDs[j], story.ID; actual != expected {
+				t.Errorf("[i=%v] Expected stories[%v].ID=%v but actual=%v", i, j, expected, actual)
+			}
+		}
+	}
+}
+
+func TestCrawlerInterrupted(t *testing.T) {
+	server := newListingServer(t)
+
+	var (
+		ctx, cancel = context.WithCancel(context.Background())
+		crawler     = Crawler{
+			Client: NoAuthClient(),
+			URL:    server.URL + "/news",
+		}
+	)
+	defer cancel()
+
+	stories := domain.Stories{}
+	_, err := crawler.Crawl(ctx, func(page domain.Stories) error {
+		stories = append(stories, page...)
+		// Simulate an interrupt arriving while the first page is processed.
+		cancel()
+		return nil
+	})
+	if !IsInterrupted(err) {
+		t.Fatalf("Expected interrupted error but actual=%v", err)
+	}
+	if expected, actual := 3, len(stories); actual != expected {
+		t.Fatalf("Expected len(stories)=%v from the first page but actual=%v", expected, actual)
+	}
+}
+
+func TestCrawlerEmptyPage(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/favorites", func(w http.ResponseWriter, req *http.Request) {
+		if req.URL.Query().Get("p") == "" {
+			fmt.Fprint(w, listingPage("favorites?p=2", 6, 5, 4))
+			return
+		}
+		// E.g. an error page or changed markup.
+		fmt.Fprint(w, "<html><body>Sorry.</body></html>")
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	testCases := []struct {
+		url         string
+		expectedIDs int
+		expectedErr error
+	}{
+		{url: server.URL + "/favorites", expectedIDs: 3, expectedErr: ErrEmptyPage},
+		// An empty first page is an empty listing.
+		{url: server.URL + "/favorites?p=2", expectedIDs: 0},
+	}
+
+	for i, testCase := range testCases {
+		crawler := Crawler{Client: NoAuthClient(), URL: testCase.url}
+		stories, _, err := crawler.Stories(context.Background())
+		if !errors.Is(err, testCase.expectedErr) {
+			t.Errorf("[i=%v] Expected err=%v but actual=%v", i, testCase.expectedErr, err)
+		}
+		if expected, actual := testCase.expectedIDs, len(stories); actual != expected {
+			t.Errorf("[i=%v] Expected len(stories)=%v but actual=%v", i, expected, actual)
+		}
+	}
+}
@@ -1,6 +1,7 @@
 package common
 
 import (
+	"errors"
 	"fmt"
 	"strings"
 	"time"
@@ -10,65 +11,236 @@ import (
 	"github.com/araddon/dateparse"
 	"jaytaylor.com/html2text"
 
-	"jaytaylor.com/hn-utils/domain"
+	"github.com/jaytaylor/hn-utils/domain"
 )
 
-// TODO: Add parsing for story root comments (e.g. "Ask HN").
+// ErrOrphanComment is returned by strict discussion parsing when a comment's
+// nesting depth doesn't match any preceding comment.
+var ErrOrphanComment = errors.New("no parent found for comment")
+
+// ParseWarning describes a comment which was dropped or re-parented while
+// parsing a discussion.
+type ParseWarning struct {
+	ID      string // Value of the comment row's id attribute.
+	Message string
+}
+
+func (w ParseWarning) String() string {
+	return fmt.Sprintf("comment %q: %s", w.ID, w.Message)
+}
 
 // ExtractDiscussion consumes an HN "/item?id=xxx" page DOM (or subset thereof)
 // and parses out all the conversation threads, returning a tree-like
 // representation of the entire discussion.
 //
+// Parsing is lenient (see Discussion.Lenient) and any warnings are discarded.
+// Use ParseDiscussion to inspect them or to fail on inconsistent nesting
+// instead.
+// Only the comments on the page passed in are considered; use a Discussion
+// (or FetchItem) for threads spanning several pages.
+//
 // If you have a *goquery.Document, simply pass it in via: doc.Selection.
 func ExtractDiscussion(doc *goquery.Selection) domain.Threads {
-	var (
-		comments = domain.Threads{}
-		parents  = map[int]*domain.Comment{} // Track each parent comment at depth X.
-	)
+	threads, _, _ := ParseDiscussion(doc, true)
+	return threads
+}
 
-	// markParent clears out any parent comments at the same or deeper depth as
-	// the passed comment before marking the new parent at depth X.
-	markParent := func(c *domain.Comment) {
-		if c.Width == 0 {
-			parents = map[int]*domain.Comment{}
-		} else {
-			for x, _ := range parents {
-				if x >= c.Width {
-					delete(parents, x)
-				}
-			}
-		}
-		parents[c.Width] = c
+// ParseDiscussion is like ExtractDiscussion, but also returns the warnings
+// collected while parsing.  When lenient is false an ErrOrphanComment error is
+// returned for inconsistent nesting, along with the threads parsed up to that
+// point.  Extraction was lossless when no warnings are returned.
+func ParseDiscussion(doc *goquery.Selection, lenient bool) (domain.Threads, []ParseWarning, error) {
+	discussion := NewDiscussion()
+	discussion.Lenient = lenient
+	err := discussion.AddPage(doc)
+	return discussion.Threads, discussion.Warnings, err
+}
+
+// CommentStatePolicy determines what discussion extraction does with dead,
+// flagged and deleted comments.  The status fields of domain.Comment are set
+// regardless of the policy, and collapsed comments are always kept.
+type CommentStatePolicy int
+
+const (
+	// MarkCommentStates keeps such comments, clearing placeholder content
+	// such as "[deleted]" so that only the status fields describe them.
+	MarkCommentStates CommentStatePolicy = iota
+	// IncludeCommentStates keeps such comments exactly as they appear on the
+	// page, placeholder content included.
+	IncludeCommentStates
+	// DropCommentStates removes such comments, attaching their replies to
+	// the nearest remaining ancestor.
+	DropCommentStates
+)
+
+var commentStatePolicyNames = map[CommentStatePolicy]string{
+	MarkCommentStates:    "mark",
+	IncludeCommentStates: "include",
+	DropCommentStates:    "drop",
+}
+
+func (p CommentStatePolicy) String() string {
+	if name, ok := commentStatePolicyNames[p]; ok {
+		return name
 	}
+	return fmt.Sprintf("CommentStatePolicy(%d)", int(p))
+}
 
-	// findParent locates a comments parent based on it's nesting depth.
-	// Nil is returned if no parent is found.
-	findParent := func(c *domain.Comment) *domain.Comment {
-		if c.Width == 0 {
-			return nil
-		}
-		if p, ok := parents[(c.Depth()-1)*domain.CommentNestingWidthIncrement]; ok {
-			return p
+// ParseCommentStatePolicy parses "mark", "include" or "drop".
+func ParseCommentStatePolicy(name string) (CommentStatePolicy, error) {
+	for p, n := range commentStatePolicyNames {
+		if n == name {
+			return p, nil
 		}
-		return nil
 	}
+	return 0, fmt.Errorf("unrecognized comment state policy %q, must be one of mark, include or drop", name)
+}
+
+// placeholderContents are shown by HN in place of the text of removed
+// comments.
+var placeholderContents = map[string]bool{
+	"[dead]":    true,
+	"[flagged]": true,
+	"[deleted]": true,
+}
+
+// Discussion assembles the conversation threads of an item whose comments are
+// split across several pages ("/item?id=xxx&p=2", ...).  The first comment on
+// a continuation page is frequently a reply to a comment on the previous page,
+// so the nesting state is carried over from one page to the next.
+type Discussion struct {
+	Threads  domain.Threads
+	Warnings []ParseWarning
+
+	// Lenient attaches orphaned comments to their nearest shallower ancestor,
+	// or to a synthetic root comment (with an ID of 0) when there is none,
+	// rather than failing with ErrOrphanComment.
+	Lenient bool
+
+	// States determines what happens to dead, flagged and deleted comments.
+	States CommentStatePolicy
+
+	parents map[int]*domain.Comment             // Track each parent comment at depth X.
+	root    *domain.Comment                     // Synthetic root for orphans.
+	dropped map[*domain.Comment]*domain.Comment // Dropped comments and the parent their replies go to.
+}
+
+// NewDiscussion returns an empty, strict Discussion.
+func NewDiscussion() *Discussion {
+	return &Discussion{
+		Threads: domain.Threads{},
+		parents: map[int]*domain.Comment{},
+		dropped: map[*domain.Comment]*domain.Comment{},
+	}
+}
+
+// AddPage parses the comments on the next page of the discussion and attaches
+// them to the tree.
+func (d *Discussion) AddPage(doc *goquery.Selection) error {
+	var err error
 
-	doc.Find(".athing.comtr").Each(func(_ int, s *goquery.Selection) {
+	doc.Find(".athing.comtr").EachWithBreak(func(_ int, s *goquery.Selection) bool {
 		c := extractComment(s)
 		if c == nil {
-			return
+			d.warn(s.AttrOr("id", ""), "dropped, invalid ID, nesting width or thread size")
+			return true
 		}
+		if d.States == MarkCommentStates && c.Removed() && placeholderContents[c.Content] {
+			c.Content = ""
+		}
+
+		var parent *domain.Comment
 		if c.Width == 0 {
-			comments = append(comments, c)
-		} else if p := findParent(c); p != nil {
-			p.Children = append(p.Children, c)
+			// Top-level comment.
+		} else if p := d.findParent(c); p != nil {
+			parent = p
+		} else if !d.Lenient {
+			err = fmt.Errorf("%w: comment=%+v", ErrOrphanComment, c)
+			return false
+		} else if p := d.findAncestor(c); p != nil {
+			d.warn(s.AttrOr("id", ""), fmt.Sprintf("no parent at width=%v, attached to ancestor %v", c.Width, p.ID))
+			parent = p
 		} else {
-			panic(fmt.Errorf("no parent found for comment=%+v", c))
+			d.warn(s.AttrOr("id", ""), fmt.Sprintf("no parent at width=%v, attached to synthetic root", c.Width))
+			if d.root == nil {
+				d.root = &domain.Comment{}
+				d.Threads = append(d.Threads, d.root)
+			}
+			parent = d.root
 		}
-		markParent(c)
+		d.attach(c, parent)
+		d.markParent(c)
+		return true
 	})
 
-	return comments
+	return err
+}
+
+// attach adds c to the replies of parent, or to the top-level threads when
+// parent is nil.  Dropped comments are passed over in favor of their own
+// parent.
+func (d *Discussion) attach(c *domain.Comment, parent *domain.Comment) {
+	for parent != nil {
+		p, ok := d.dropped[parent]
+		if !ok {
+			break
+		}
+		parent = p
+	}
+
+	if d.States == DropCommentStates && c.Removed() {
+		d.dropped[c] = parent
+		return
+	}
+
+	if parent == nil {
+		d.Threads = append(d.Threads, c)
+	} else {
+		parent.Children = append(parent.Children, c)
+	}
+}
+
+func (d *Discussion) warn(id string, message string) {
+	d.Warnings = append(d.Warnings, ParseWarning{ID: id, Message: message})
+}
+
+// markParent clears out any parent comments at the same or deeper depth as
+// the passed comment before marking the new parent at depth X.
+func (d *Discussion) markParent(c *domain.Comment) {
+	if c.Width == 0 {
+		d.parents = map[int]*domain.Comment{}
+	} else {
+		for x, _ := range d.parents {
+			if x >= c.Width {
+				delete(d.parents, x)
+			}
+		}
+	}
+	d.parents[c.Width] = c
+}
+
+// findParent locates a comments parent based on it's nesting depth.
+// Nil is returned if no parent is found.
+func (d *Discussion) findParent(c *domain.Comment) *domain.Comment {
+	if c.Width == 0 {
+		return nil
+	}
+	if p, ok := d.parents[(c.Depth()-1)*domain.CommentNestingWidthIncrement]; ok {
+		return p
+	}
+	return nil
+}
+
+// findAncestor locates the deepest tracked comment shallower than c.  Nil is
+// returned if there is none.
+func (d *Discussion) findAncestor(c *domain.Comment) *domain.Comment {
+	var ancestor *domain.Comment
+	for x, p := range d.parents {
+		if x < c.Width && (ancestor == nil || x > ancestor.Width) {
+			ancestor = p
+		}
+	}
+	return ancestor
 }
 
 // extractComment returns nil if no comment was found, or the ID or width parse
@@ -80,15 +252,35 @@ func extractComment(s *goquery.Selection) *domain.Comment {
 			s.Remove()
 		}
 	})
-	content, _ := html2text.FromHTMLNode(s.Find(".commtext").Get(0))
+
+	// Removed comments have no .commtext, only a placeholder such as
+	// "[deleted]" in the .comment container.
+	var (
+		commtext = s.Find(".commtext").First()
+		content  string
+	)
+	if commtext.Length() > 0 {
+		content, _ = html2text.FromHTMLNode(commtext.Get(0))
+	} else {
+		s.Find(".comment .reply").Remove()
+		content = strings.TrimSpace(s.Find(".comment").First().Text())
+	}
+	comhead := s.Find(".comhead").First().Text()
 
 	c := &domain.Comment{
 		ID:        Int64Or(s.AttrOr("id", "0"), -1),
 		Author:    s.Find("a.hnuser").First().Text(),
-		Timestamp: parseAge(s.Find(".age").Text()),
+		Timestamp: extractTimestamp(s.Find(".age").First()),
 		Content:   content,
 		N:         int(Int64Or(s.Find(".togg").AttrOr("n", "0"), -1)),
-		Width:     int(Int64Or(s.Find("img").First().AttrOr("width", "0"), -1)),
+		Width:     extractWidth(s),
+		HTML:      CommentHTML(commtext),
+		Markdown:  CommentMarkdown(commtext),
+		Links:     CommentLinks(commtext),
+		Dead:      strings.Contains(comhead, "[dead]") || commtext.HasClass("cdd") || content == "[dead]",
+		Flagged:   strings.Contains(comhead, "[flagged]") || content == "[flagged]",
+		Deleted:   content == "[deleted]",
+		Collapsed: s.HasClass("coll") || s.HasClass("noshow"),
 	}
 
 	if c != nil && (c.ID == -1 || c.N == -1 || c.Width == -1) {
@@ -97,6 +289,50 @@ func extractComment(s *goquery.Selection) *domain.Comment {
 	return c
 }
 
+// extractWidth returns the nesting width of a comment row.  The depth carried
+// by current markup in td.ind[indent] is preferred, falling back to the width
+// of the spacer image for old markup.  -1 is returned for an invalid value.
+func extractWidth(s *goquery.Selection) int {
+	if indent, ok := s.Find("td.ind").First().Attr("indent"); ok {
+		if depth := Int64Or(indent, -1); depth >= 0 {
+			return int(depth) * domain.CommentNestingWidthIncrement
+		}
+		return -1
+	}
+	return int(Int64Or(s.Find("img").First().AttrOr("width", "0"), -1))
+}
+
+// extractTimestamp returns the time of an .age element.  The exact time in
+// its title attribute is preferred, falling back to parsing the human-style
+// age for old markup.
+func extractTimestamp(age *goquery.Selection) time.Time {
+	if title, ok := age.Attr("title"); ok {
+		if ts, ok := parseAgeTitle(title); ok {
+			return ts
+		}
+	}
+	return parseAge(age.Text())
+}
+
+// parseAgeTitle parses the title attribute of an .age element, which is
+// either "2006-01-02T15:04:05" (UTC) or that followed by the Unix timestamp.
+func parseAgeTitle(title string) (time.Time, bool) {
+	fields := strings.Fields(title)
+	if len(fields) == 0 {
+		return time.Time{}, false
+	}
+	if len(fields) > 1 {
+		if unix := Int64Or(fields[1], -1); unix > 0 {
+			return time.Unix(unix, 0).UTC(), true
+		}
+	}
+	ts, err := time.ParseInLocation("2006-01-02T15:04:05", fields[0], time.UTC)
+	if err != nil {
+		return time.Time{}, false
+	}
+	return ts, true
+}
+
 // parseAge converts any HN time string into a Go time.Time struct.
 func parseAge(humanTime string) time.Time {
 	// For items favorited in the early days of the feature, HN spits out
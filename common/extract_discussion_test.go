@@ -1,8 +1,10 @@
 package common
 
 import (
+	"errors"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/PuerkitoBio/goquery"
 )
@@ -139,3 +141,217 @@ const commentTreeHTML = `
       </table></center></body><script type='text/javascript' src='hn.js?tR69YSVmkcPOlJWQ6QcH'></script>
   </html>
 `
+
+func TestParseDiscussion(t *testing.T) {
+	testCases := []struct {
+		comments         [][2]int
+		lenient          bool
+		expectError      bool
+		expectedLen      int
+		expectedThreads  int
+		expectedWarnings int
+	}{
+		{
+			comments:        [][2]int{{1, 0}, {2, 40}, {3, 80}, {4, 0}},
+			expectedLen:     4,
+			expectedThreads: 2,
+		},
+		{
+			comments:        [][2]int{{1, 0}, {2, 80}},
+			expectError:     true,
+			expectedLen:     1,
+			expectedThreads: 1,
+		},
+		{
+			comments:         [][2]int{{1, 0}, {2, 80}, {3, 120}},
+			lenient:          true,
+			expectedLen:      3,
+			expectedThreads:  1,
+			expectedWarnings: 1,
+		},
+		{
+			// A continuation page parsed on its own; the orphans end up
+			// under the synthetic root.
+			comments:         [][2]int{{1, 40}, {2, 80}, {3, 40}, {4, 0}},
+			lenient:          true,
+			expectedLen:      5,
+			expectedThreads:  2,
+			expectedWarnings: 2,
+		},
+	}
+
+	for i, testCase := range testCases {
+		doc, err := goquery.NewDocumentFromReader(strings.NewReader(discussionPage("", testCase.comments...)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		threads, warnings, err := ParseDiscussion(doc.Selection, testCase.lenient)
+		if testCase.expectError {
+			if !errors.Is(err, ErrOrphanComment) {
+				t.Errorf("[i=%v] Expected ErrOrphanComment but actual=%v", i, err)
+			}
+		} else if err != nil {
+			t.Errorf("[i=%v] Unexpected error: %s", i, err)
+		}
+		if expected, actual := testCase.expectedLen, threads.Len(); actual != expected {
+			t.Errorf("[i=%v] Expected threads.Len()=%v but actual=%v", i, expected, actual)
+		}
+		if expected, actual := testCase.expectedThreads, len(threads); actual != expected {
+			t.Errorf("[i=%v] Expected len(threads)=%v but actual=%v", i, expected, actual)
+		}
+		if expected, actual := testCase.expectedWarnings, len(warnings); actual != expected {
+			t.Errorf("[i=%v] Expected len(warnings)=%v but actual=%v (%v)", i, expected, actual, warnings)
+		}
+	}
+}
+
+func TestParseDiscussionInvalidComment(t *testing.T) {
+	html := strings.Replace(discussionPage("", [2]int{1, 0}, [2]int{2, 0}), `id="2"`, `id="bogus"`, 1)
+	doc, err := goquery.NewDocumentFromReader(strings.NewReader(html))
+	if err != nil {
+		t.Fatal(err)
+	}
+	threads, warnings, err := ParseDiscussion(doc.Selection, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected, actual := 1, threads.Len(); actual != expected {
+		t.Errorf("Expected threads.Len()=%v but actual=%v", expected, actual)
+	}
+	if expected, actual := 1, len(warnings); actual != expected {
+		t.Fatalf("Expected len(warnings)=%v but actual=%v", expected, actual)
+	}
+	if expected, actual := "bogus", warnings[0].ID; actual != expected {
+		t.Errorf("Expected warnings[0].ID=%q but actual=%q", expected, actual)
+	}
+}
+
+func TestExtractCommentCurrentMarkup(t *testing.T) {
+	// The spacer image width disagrees with the indent attribute on purpose,
+	// to verify the latter takes precedence.
+	html := `<table><tr class="athing comtr" id="42"><td><table><tr><td class="ind" indent="3"><img src="s.gif" height="1" width="0"></td><td class="default"><span class="comhead"><a href="user?id=alice" class="hnuser">alice</a> <span class="age" title="2019-01-15T19:45:00 1547581500"><a href="item?id=42">6 days ago</a></span> <a class="togg" n="1"></a></span><div class="comment"><span class="commtext c00">Hello.</span></div></td></tr></table></td></tr></table>`
+	doc, err := goquery.NewDocumentFromReader(strings.NewReader(html))
+	if err != nil {
+		t.Fatal(err)
+	}
+	comment := extractComment(doc.Selection)
+	if comment == nil {
+		t.Fatal("Expected comment but got nil")
+	}
+	if expected, actual := 3, comment.Depth(); actual != expected {
+		t.Errorf("Expected comment.Depth()=%v but actual=%v", expected, actual)
+	}
+	if expected, actual := time.Date(2019, 1, 15, 19, 45, 0, 0, time.UTC), comment.Timestamp; !actual.Equal(expected) {
+		t.Errorf("Expected comment.Timestamp=%v but actual=%v", expected, actual)
+	}
+}
+
+func TestParseAgeTitle(t *testing.T) {
+	testCases := []struct {
+		title    string
+		expected time.Time
+		ok       bool
+	}{
+		{"2019-01-15T19:45:00 1547581500", time.Date(2019, 1, 15, 19, 45, 0, 0, time.UTC), true},
+		{"2019-01-15T19:45:00", time.Date(2019, 1, 15, 19, 45, 0, 0, time.UTC), true},
+		{"2019-01-15T19:45:00 bogus", time.Date(2019, 1, 15, 19, 45, 0, 0, time.UTC), true},
+		{"6 days ago", time.Time{}, false},
+		{"", time.Time{}, false},
+	}
+
+	for i, testCase := range testCases {
+		actual, ok := parseAgeTitle(testCase.title)
+		if ok != testCase.ok {
+			t.Errorf("[i=%v] Expected ok=%v but actual=%v", i, testCase.ok, ok)
+		}
+		if !actual.Equal(testCase.expected) {
+			t.Errorf("[i=%v] Expected ts=%v but actual=%v", i, testCase.expected, actual)
+		}
+	}
+}
+
+func TestDiscussionCommentStates(t *testing.T) {
+	testCases := []struct {
+		states          CommentStatePolicy
+		expectedLen     int
+		expectedContent string // Of the deleted comment, when kept.
+	}{
+		{MarkCommentStates, 6, ""},
+		{IncludeCommentStates, 6, "[deleted]"},
+		{DropCommentStates, 3, ""},
+	}
+
+	for i, testCase := range testCases {
+		doc, err := goquery.NewDocumentFromReader(strings.NewReader(commentStatesHTML))
+		if err != nil {
+			t.Fatal(err)
+		}
+		discussion := NewDiscussion()
+		discussion.States = testCase.states
+		if err := discussion.AddPage(doc.Selection); err != nil {
+			t.Fatalf("[i=%v] Unexpected error: %s", i, err)
+		}
+		threads := discussion.Threads
+
+		if expected, actual := testCase.expectedLen, threads.Len(); actual != expected {
+			t.Fatalf("[i=%v] Expected threads.Len()=%v but actual=%v", i, expected, actual)
+		}
+
+		if testCase.states == DropCommentStates {
+			// The reply to the deleted comment moves up to its grandparent.
+			if expected, actual := int64(3), threads[0].Children[0].ID; actual != expected {
+				t.Errorf("[i=%v] Expected threads[0].Children[0].ID=%v but actual=%v", i, expected, actual)
+			}
+			if expected, actual := true, threads[1].Collapsed; actual != expected {
+				t.Errorf("[i=%v] Expected threads[1].Collapsed=%v but actual=%v", i, expected, actual)
+			}
+			continue
+		}
+
+		deleted := threads[0].Children[0]
+		if expected, actual := true, deleted.Deleted; actual != expected {
+			t.Errorf("[i=%v] Expected deleted.Deleted=%v but actual=%v", i, expected, actual)
+		}
+		if expected, actual := testCase.expectedContent, deleted.Content; actual != expected {
+			t.Errorf("[i=%v] Expected deleted.Content=%q but actual=%q", i, expected, actual)
+		}
+		if expected, actual := true, threads[1].Dead; actual != expected {
+			t.Errorf("[i=%v] Expected threads[1].Dead=%v but actual=%v", i, expected, actual)
+		}
+		if expected, actual := "Killed comment, shown with showdead.", threads[1].Content; actual != expected {
+			t.Errorf("[i=%v] Expected threads[1].Content=%q but actual=%q", i, expected, actual)
+		}
+		if expected, actual := true, threads[2].Flagged; actual != expected {
+			t.Errorf("[i=%v] Expected threads[2].Flagged=%v but actual=%v", i, expected, actual)
+		}
+		if expected, actual := true, threads[3].Collapsed; actual != expected {
+			t.Errorf("[i=%v] Expected threads[3].Collapsed=%v but actual=%v", i, expected, actual)
+		}
+	}
+}
+
+func TestParseCommentStatePolicy(t *testing.T) {
+	for _, policy := range []CommentStatePolicy{MarkCommentStates, IncludeCommentStates, DropCommentStates} {
+		actual, err := ParseCommentStatePolicy(policy.String())
+		if err != nil {
+			t.Errorf("Unexpected error parsing %v: %s", policy, err)
+		}
+		if actual != policy {
+			t.Errorf("Expected policy=%v but actual=%v", policy, actual)
+		}
+	}
+	if _, err := ParseCommentStatePolicy("hide"); err == nil {
+		t.Errorf("Expected error parsing invalid policy but got none")
+	}
+}
+
+const commentStatesHTML = `
+<table class="comment-tree">
+  <tr class="athing comtr" id="1"><td><table><tr><td class="ind" indent="0"></td><td class="default"><span class="comhead"><a href="user?id=alice" class="hnuser">alice</a> <a class="togg" n="3"></a></span><div class="comment"><span class="commtext c00">Alive and well.</span><div class="reply"><a href="reply?id=1">reply</a></div></div></td></tr></table></td></tr>
+  <tr class="athing comtr" id="2"><td><table><tr><td class="ind" indent="1"></td><td class="default"><span class="comhead"> <a class="togg" n="2"></a></span><div class="comment"> [deleted] </div></td></tr></table></td></tr>
+  <tr class="athing comtr" id="3"><td><table><tr><td class="ind" indent="2"></td><td class="default"><span class="comhead"><a href="user?id=bob" class="hnuser">bob</a> <a class="togg" n="1"></a></span><div class="comment"><span class="commtext c00">Replying to a deleted comment.</span></div></td></tr></table></td></tr>
+  <tr class="athing comtr" id="4"><td><table><tr><td class="ind" indent="0"></td><td class="default"><span class="comhead"><a href="user?id=spammer" class="hnuser">spammer</a> [dead] <a class="togg" n="1"></a></span><div class="comment"><span class="commtext cdd">Killed comment, shown with showdead.</span></div></td></tr></table></td></tr>
+  <tr class="athing comtr" id="5"><td><table><tr><td class="ind" indent="0"></td><td class="default"><span class="comhead"><a href="user?id=troll" class="hnuser">troll</a> [flagged] <a class="togg" n="1"></a></span><div class="comment"><span class="commtext c00">Flamebait.</span></div></td></tr></table></td></tr>
+  <tr class="athing comtr coll" id="6"><td><table><tr><td class="ind" indent="0"></td><td class="default"><span class="comhead"><a href="user?id=carol" class="hnuser">carol</a> <a class="togg" n="1"></a></span><div class="comment noshow"><span class="commtext c00">Folded away.</span></div></td></tr></table></td></tr>
+</table>
+`
@@ -0,0 +1,120 @@
+package common
+
+import (
+	"context"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+	"jaytaylor.com/html2text"
+
+	"github.com/jaytaylor/hn-utils/domain"
+)
+
+// ExtractItem consumes an HN "/item?id=xxx" page DOM and returns the story
+// header along with its text body (for e.g. "Ask HN" posts), poll options and
+// its entire discussion.
+//
+// If you have a *goquery.Document, simply pass it in via: doc.Selection.
+func ExtractItem(doc *goquery.Selection) domain.Story {
+	story := ExtractStory(doc.Find(".fatitem .athing").First())
+	story.Text, story.TextHTML = ExtractStoryText(doc)
+	story.PollOptions = ExtractPollOptions(doc)
+	story.Children = ExtractDiscussion(doc)
+	return story
+}
+
+// FetchItem retrieves the item page along with every continuation page of its
+// discussion, following the "More" links, and returns the story with the
+// complete discussion tree.  Comments with inconsistent nesting are
+// re-parented (see Discussion.Lenient) and reported in the returned warnings;
+// extraction was lossless when there are none.  On error the partially
+// assembled story is returned.
+func FetchItem(ctx context.Context, get GetFunc, page string) (domain.Story, []ParseWarning, error) {
+	discussion := NewDiscussion()
+	discussion.Lenient = true
+	story, err := discussion.Fetch(ctx, get, page)
+	return story, discussion.Warnings, err
+}
+
+// Fetch is like FetchItem, but assembles the discussion according to the
+// settings of d, which must be empty.  Warnings are collected in d.Warnings.
+func (d *Discussion) Fetch(ctx context.Context, get GetFunc, page string) (domain.Story, error) {
+	var (
+		story domain.Story
+		pages int
+	)
+
+	err := walk(ctx, get, page, func(doc *goquery.Document) (bool, error) {
+		if pages == 0 {
+			story = ExtractStory(doc.Find(".fatitem .athing").First())
+			story.Text, story.TextHTML = ExtractStoryText(doc.Selection)
+			story.PollOptions = ExtractPollOptions(doc.Selection)
+		}
+		pages++
+		return true, d.AddPage(doc.Selection)
+	})
+
+	story.Children = d.Threads
+	return story, err
+}
+
+// ExtractStoryText returns the plain text and original HTML of the story's own
+// text body, or empty strings when the item is a plain link submission.
+func ExtractStoryText(doc *goquery.Selection) (string, string) {
+	body := doc.Find(".fatitem .toptext").First()
+	if body.Length() == 0 {
+		// Older markup places the text in an unlabeled cell of the row
+		// between the subtext and the poll options or reply form.
+		doc.Find(".fatitem .subtext").First().Closest("tr").NextAll().EachWithBreak(func(_ int, row *goquery.Selection) bool {
+			if row.Find("form, .pollopt, .athing").Length() > 0 {
+				return false
+			}
+			if cell := row.Children().Last(); cell.Length() > 0 && strings.TrimSpace(cell.Text()) != "" {
+				body = cell
+				return false
+			}
+			return true
+		})
+	}
+	if body.Length() == 0 {
+		return "", ""
+	}
+
+	html, err := body.Html()
+	if err != nil {
+		return "", ""
+	}
+	text, _ := html2text.FromHTMLNode(body.Get(0))
+	return text, strings.TrimSpace(html)
+}
+
+// ExtractPollOptions returns the options of a poll item page along with their
+// current scores, or nil when the item isn't a poll.
+func ExtractPollOptions(doc *goquery.Selection) []domain.PollOption {
+	var options []domain.PollOption
+	doc.Find(".fatitem .pollopt").Each(func(_ int, opt *goquery.Selection) {
+		// Option rows are followed by a row holding the score.
+		row := opt.Closest("tr")
+		score := row.Find(".score")
+		if score.Length() == 0 {
+			score = row.Next().Find(".score")
+		}
+
+		id := Int64Or(row.AttrOr("id", ""), -1)
+		if id == -1 {
+			id = Int64Or(strings.TrimPrefix(score.AttrOr("id", ""), "score_"), -1)
+		}
+
+		text := opt.Find(".commtext").First()
+		if text.Length() == 0 {
+			text = opt
+		}
+
+		options = append(options, domain.PollOption{
+			ID:     id,
+			Text:   strings.TrimSpace(text.Text()),
+			Points: Int64Or(numExpr.ReplaceAllString(score.Text(), "$1"), 0),
+		})
+	})
+	return options
+}
@@ -0,0 +1,247 @@
+package common
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/PuerkitoBio/goquery"
+
+	"github.com/jaytaylor/hn-utils/domain"
+)
+
+func TestExtractItem(t *testing.T) {
+	doc, err := goquery.NewDocumentFromReader(strings.NewReader(storyItemHTML))
+	if err != nil {
+		t.Fatal(err)
+	}
+	story := ExtractItem(doc.Selection)
+
+	if expected, actual := int64(18914411), story.ID; actual != expected {
+		t.Errorf("Expected story.ID=%v but actual=%v", expected, actual)
+	}
+	if expected, actual := "Brexit Deal Fails in Parliament", story.Title; actual != expected {
+		t.Errorf("Expected story.Title=%q but actual=%q", expected, actual)
+	}
+	if expected, actual := int64(612), story.Points; actual != expected {
+		t.Errorf("Expected story.Points=%v but actual=%v", expected, actual)
+	}
+	if expected, actual := int64(3), story.Comments; actual != expected {
+		t.Errorf("Expected story.Comments=%v but actual=%v", expected, actual)
+	}
+	if expected, actual := "tosh", story.Submitter; actual != expected {
+		t.Errorf("Expected story.Submitter=%q but actual=%q", expected, actual)
+	}
+	if expected, actual := 3, story.Children.Len(); actual != expected {
+		t.Errorf("Expected story.Children.Len()=%v but actual=%v", expected, actual)
+	}
+	if expected, actual := 2, len(story.Children); actual != expected {
+		t.Fatalf("Expected len(story.Children)=%v but actual=%v", expected, actual)
+	}
+	if expected, actual := 1, len(story.Children[0].Children); actual != expected {
+		t.Errorf("Expected len(story.Children[0].Children)=%v but actual=%v", expected, actual)
+	}
+}
+
+const storyItemHTML = `
+<html op="item"><head><title>Brexit Deal Fails in Parliament | Hacker News</title></head><body><center><table id="hnmain" border="0" cellpadding="0" cellspacing="0" width="85%" bgcolor="#f6f6ef">
+<tr><td><table class="fatitem" border="0">
+  <tr class='athing' id='18914411'>
+      <td align="right" valign="top" class="title"><span class="rank"></span></td>      <td valign="top" class="votelinks"><center><a id='up_18914411' href='vote?id=18914411&amp;how=up&amp;goto=item%3Fid%3D18914411'><div class='votearrow' title='upvote'></div></a></center></td><td class="title"><a href="https://www.nytimes.com/2019/01/15/world/europe/brexit-vote.html" class="storylink">Brexit Deal Fails in Parliament</a><span class="sitebit comhead"> (<a href="from?site=nytimes.com"><span class="sitestr">nytimes.com</span></a>)</span></td></tr><tr><td colspan="2"></td><td class="subtext">
+        <span class="score" id="score_18914411">612 points</span> by <a href="user?id=tosh" class="hnuser">tosh</a> <span class="age"><a href="item?id=18914411">12 days ago</a></span> <span id="unv_18914411"></span> | <a href="hide?id=18914411&amp;goto=item%3Fid%3D18914411">hide</a> | <a href="https://hn.algolia.com/?query=Brexit%20Deal%20Fails%20in%20Parliament&sort=byDate&dateRange=all&type=story&storyText=false&prefix&page=0" class="hnpast">past</a> | <a href="https://www.google.com/search?q=Brexit%20Deal%20Fails%20in%20Parliament">web</a> | <a href="item?id=18914411">3&nbsp;comments</a>              </td></tr>
+      <tr style="height:10px"></tr><tr><td colspan="2"></td><td>
+          <form method="post" action="comment"><input type="hidden" name="parent" value="18914411"><textarea name="text" rows="6" cols="60"></textarea>
+                <br><br><input type="submit" value="add comment"></form>
+      </td></tr>
+  </table><br><br>
+  <table border="0" class='comment-tree'>
+    <tr class='athing comtr ' id='18914500'><td>
+      <table border='0'>  <tr>    <td class='ind'><img src="s.gif" height="1" width="0"></td><td valign="top" class="votelinks"></td><td class="default"><div style="margin-top:2px; margin-bottom:-10px;"><span class="comhead">
+        <a href="user?id=alice" class="hnuser">alice</a> <span class="age"><a href="item?id=18914500">12 days ago</a></span> <span class="par"></span> <a class="togg" n="2" href="javascript:void(0)"></a></span></div><br><div class="comment">
+        <span class="commtext c00">First top-level comment.</span>
+        <div class='reply'><p><font size="1"><u><a href="reply?id=18914500">reply</a></u></font></div></div></td></tr>
+      </table></td></tr>
+    <tr class='athing comtr ' id='18914600'><td>
+      <table border='0'>  <tr>    <td class='ind'><img src="s.gif" height="1" width="40"></td><td valign="top" class="votelinks"></td><td class="default"><div style="margin-top:2px; margin-bottom:-10px;"><span class="comhead">
+        <a href="user?id=bob" class="hnuser">bob</a> <span class="age"><a href="item?id=18914600">12 days ago</a></span> <span class="par"></span> <a class="togg" n="1" href="javascript:void(0)"></a></span></div><br><div class="comment">
+        <span class="commtext c00">A reply.</span>
+        <div class='reply'><p><font size="1"><u><a href="reply?id=18914600">reply</a></u></font></div></div></td></tr>
+      </table></td></tr>
+    <tr class='athing comtr ' id='18914700'><td>
+      <table border='0'>  <tr>    <td class='ind'><img src="s.gif" height="1" width="0"></td><td valign="top" class="votelinks"></td><td class="default"><div style="margin-top:2px; margin-bottom:-10px;"><span class="comhead">
+        <a href="user?id=carol" class="hnuser">carol</a> <span class="age"><a href="item?id=18914700">12 days ago</a></span> <span class="par"></span> <a class="togg" n="1" href="javascript:void(0)"></a></span></div><br><div class="comment">
+        <span class="commtext c00">Second top-level comment.</span>
+        <div class='reply'><p><font size="1"><u><a href="reply?id=18914700">reply</a></u></font></div></div></td></tr>
+      </table></td></tr>
+  </table>
+</td></tr></table></center></body></html>
+`
+
+func TestExtractStoryText(t *testing.T) {
+	testCases := []struct {
+		html         string
+		expectedText string
+		expectedHTML string
+	}{
+		{
+			html:         storyItemHTML,
+			expectedText: "",
+			expectedHTML: "",
+		},
+		{
+			html:         askItemHTML,
+			expectedText: "What are you working on?\n\nAnything goes.",
+			expectedHTML: "What are you working on?<p>Anything goes.</p>",
+		},
+		{
+			html:         `<table class="fatitem"><tr class="athing" id="1"><td class="title"><a class="storylink">Show HN: Thing</a></td></tr><tr><td class="subtext"></td></tr><tr><td colspan="2"></td><td><div class="toptext">I made <i>this</i>.</div></td></tr></table>`,
+			expectedText: "I made this.",
+			expectedHTML: "I made <i>this</i>.",
+		},
+	}
+
+	for i, testCase := range testCases {
+		doc, err := goquery.NewDocumentFromReader(strings.NewReader(testCase.html))
+		if err != nil {
+			t.Fatal(err)
+		}
+		text, html := ExtractStoryText(doc.Selection)
+		if expected, actual := testCase.expectedText, text; actual != expected {
+			t.Errorf("[i=%v] Expected text=%q but actual=%q", i, expected, actual)
+		}
+		if expected, actual := testCase.expectedHTML, html; actual != expected {
+			t.Errorf("[i=%v] Expected html=%q but actual=%q", i, expected, actual)
+		}
+	}
+}
+
+const askItemHTML = `
+<html op="item"><head><title>Ask HN: What are you working on? | Hacker News</title></head><body><center><table id="hnmain" border="0" cellpadding="0" cellspacing="0" width="85%" bgcolor="#f6f6ef">
+<tr><td><table class="fatitem" border="0">
+  <tr class='athing' id='18915000'>
+      <td align="right" valign="top" class="title"><span class="rank"></span></td>      <td valign="top" class="votelinks"><center><a id='up_18915000' href='vote?id=18915000&amp;how=up&amp;goto=item%3Fid%3D18915000'><div class='votearrow' title='upvote'></div></a></center></td><td class="title"><a href="item?id=18915000" class="storylink">Ask HN: What are you working on?</a></td></tr><tr><td colspan="2"></td><td class="subtext">
+        <span class="score" id="score_18915000">42 points</span> by <a href="user?id=dang" class="hnuser">dang</a> <span class="age"><a href="item?id=18915000">2 hours ago</a></span> <span id="unv_18915000"></span> | <a href="item?id=18915000">discuss</a>              </td></tr>
+      <tr style="height:2px"></tr><tr><td colspan="2"></td><td>What are you working on?<p>Anything goes.</p></td></tr>        <tr style="height:10px"></tr><tr><td colspan="2"></td><td>
+          <form method="post" action="comment"><input type="hidden" name="parent" value="18915000"><textarea name="text" rows="6" cols="60"></textarea>
+                <br><br><input type="submit" value="add comment"></form>
+      </td></tr>
+  </table><br><br>
+  <table border="0" class='comment-tree'></table>
+</td></tr></table></center></body></html>
+`
+
+// discussionPage renders a minimal HN item page holding the given comments
+// (pairs of ID and nesting width) and an optional "More" link.
+func discussionPage(more string, comments ...[2]int) string {
+	html := `<html><body><table class="fatitem"><tr class="athing" id="1"><td class="title"><a class="storylink" href="https://example.com/">Big thread</a></td></tr>`
+	html += `<tr><td class="subtext"><span class="score">99 points</span> <a href="user?id=pg" class="hnuser">pg</a> <a href="item?id=1">2000 comments</a></td></tr></table>`
+	html += `<table class="comment-tree">`
+	for _, c := range comments {
+		html += fmt.Sprintf(`<tr class="athing comtr" id="%v"><td><table><tr><td class="ind"><img src="s.gif" height="1" width="%v"></td><td class="default"><span class="comhead"><a href="user?id=u%v" class="hnuser">u%v</a> <a class="togg" n="1"></a></span><div class="comment"><span class="commtext c00">Comment %v</span></div></td></tr></table></td></tr>`, c[0], c[1], c[0], c[0], c[0])
+	}
+	html += `</table>`
+	if len(more) > 0 {
+		html += fmt.Sprintf(`<a href="%v" class="morelink" rel="next">More</a>`, more)
+	}
+	return html + "</body></html>"
+}
+
+func TestFetchItemPaginated(t *testing.T) {
+	const (
+		total   = 2000
+		perPage = 301
+	)
+
+	// Nesting cycles through depths 0..4 so that most continuation pages
+	// begin with a reply to a comment on the previous page.
+	comments := make([][2]int, 0, total)
+	for i := 0; i < total; i++ {
+		comments = append(comments, [2]int{100 + i, (i % 5) * domain.CommentNestingWidthIncrement})
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/item", func(w http.ResponseWriter, req *http.Request) {
+		p, _ := strconv.Atoi(req.URL.Query().Get("p"))
+		if p < 1 {
+			p = 1
+		}
+		start, end, more := (p-1)*perPage, p*perPage, fmt.Sprintf("item?id=1&p=%v", p+1)
+		if end >= total {
+			end, more = total, ""
+		}
+		fmt.Fprint(w, discussionPage(more, comments[start:end]...))
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	get := func(ctx context.Context, page string) (*goquery.Document, error) {
+		return GetDocument(ctx, server.Client(), page)
+	}
+	story, warnings, err := FetchItem(context.Background(), get, server.URL+"/item?id=1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(warnings) > 0 {
+		t.Errorf("Expected no warnings but actual=%v", warnings)
+	}
+
+	if expected, actual := "Big thread", story.Title; actual != expected {
+		t.Errorf("Expected story.Title=%q but actual=%q", expected, actual)
+	}
+	if expected, actual := total, story.Children.Len(); actual != expected {
+		t.Errorf("Expected story.Children.Len()=%v but actual=%v", expected, actual)
+	}
+	if expected, actual := total/5, len(story.Children); actual != expected {
+		t.Errorf("Expected len(story.Children)=%v but actual=%v", expected, actual)
+	}
+}
+
+func TestExtractPollOptions(t *testing.T) {
+	doc, err := goquery.NewDocumentFromReader(strings.NewReader(pollItemHTML))
+	if err != nil {
+		t.Fatal(err)
+	}
+	story := ExtractItem(doc.Selection)
+
+	if expected, actual := domain.PollStory, story.Kind; actual != expected {
+		t.Errorf("Expected story.Kind=%v but actual=%v", expected, actual)
+	}
+	expected := []domain.PollOption{
+		{ID: 126810, Text: "Python", Points: 1061},
+		{ID: 126811, Text: "Go", Points: 487},
+	}
+	if !reflect.DeepEqual(story.PollOptions, expected) {
+		t.Errorf("Expected story.PollOptions=%+v but actual=%+v", expected, story.PollOptions)
+	}
+	if story.Text != "" || story.TextHTML != "" {
+		t.Errorf("Expected empty story.Text and story.TextHTML but actual=%q, %q", story.Text, story.TextHTML)
+	}
+
+	doc, err = goquery.NewDocumentFromReader(strings.NewReader(storyItemHTML))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if actual := ExtractPollOptions(doc.Selection); actual != nil {
+		t.Errorf("Expected no poll options but actual=%+v", actual)
+	}
+}
+
+const pollItemHTML = `
+<html op="item"><body><table class="fatitem" border="0">
+  <tr class='athing' id='126809'><td align="right" valign="top" class="title"><span class="rank"></span></td><td class="votelinks"></td><td class="title"><span class="titleline"><a href="item?id=126809">Which language do you use most?</a></span></td></tr>
+  <tr><td colspan="2"></td><td class="subtext"><span class="score" id="score_126809">400 points</span> by <a href="user?id=pg" class="hnuser">pg</a> <span class="age" title="2008-02-24T21:17:52 1203887872"><a href="item?id=126809">on Feb 24, 2008</a></span> | <a href="item?id=126809">1&nbsp;comment</a></td></tr>
+  <tr style="height:10px"></tr><tr><td colspan="2"></td><td><table>
+    <tr class="athing" id="126810"><td valign="top" class="votelinks"></td><td class="comment pollopt"><div><span class="commtext c00">Python</span></div></td></tr>
+    <tr><td></td><td class="default"><span class="comhead"><span class="score" id="score_126810">1061 points</span></span></td></tr>
+    <tr style="height:7px"></tr>
+    <tr class="athing" id="126811"><td valign="top" class="votelinks"></td><td class="comment pollopt"><div><span class="commtext c00">Go</span></div></td></tr>
+    <tr><td></td><td class="default"><span class="comhead"><span class="score" id="score_126811">487 points</span></span></td></tr>
+  </table></td></tr>
+</table>
+<table class="comment-tree"></table></body></html>
+`
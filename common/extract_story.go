@@ -4,11 +4,8 @@ import (
 	"fmt"
 	"regexp"
 	"strings"
-	"time"
 
 	"github.com/PuerkitoBio/goquery"
-	"github.com/araddon/dateparse"
-	"github.com/gigawattio/ago"
 	"github.com/jaytaylor/hn-utils/domain"
 )
 
@@ -17,42 +14,89 @@ var (
 	hnuserExpr = regexp.MustCompile(`^user\?id=`)
 )
 
+// ExtractStory consumes the ".athing" row of a story (on a listing or item
+// page) and parses out the story metadata from it and the subtext row which
+// follows it.
+//
+// Job posts have no score or submitter and stories without comments show
+// "discuss" instead of a count; both have 0 points and comments.
 func ExtractStory(s *goquery.Selection) domain.Story {
 	var (
-		title    = s.Find(".title a.storylink")
-		comments = s.Next().Find("a").Last()
-		story    = domain.Story{
-			ID:          Int64Or(s.AttrOr("id", "0"), -1),
-			Title:       title.Text(),
-			URL:         ReconstructHNURL(title.AttrOr("href", "")),
-			Points:      Int64Or(numExpr.ReplaceAllString(s.Next().Find(".score").Text(), "$1"), -1),
-			Comments:    Int64Or(numExpr.ReplaceAllString(comments.Text(), "$1"), -1),
-			CommentsURL: comments.AttrOr("href", ""),
-			Submitter:   hnuserExpr.ReplaceAllString(s.Next().Find(".hnuser").AttrOr("href", ""), ""),
-		}
+		title    = s.Find(".titleline > a").First()
+		subtext  = s.Next()
+		comments = subtext.Find("a").FilterFunction(func(_ int, a *goquery.Selection) bool {
+			text := strings.TrimSpace(a.Text())
+			return text == "discuss" || strings.Contains(text, "comment")
+		}).Last()
 	)
+	if title.Length() == 0 {
+		title = s.Find(".title a.storylink").First()
+	}
+
+	story := domain.Story{
+		ID:          Int64Or(s.AttrOr("id", "0"), -1),
+		Rank:        int(Int64Or(strings.TrimSuffix(strings.TrimSpace(s.Find(".rank").Text()), "."), 0)),
+		Title:       title.Text(),
+		URL:         ReconstructHNURL(title.AttrOr("href", "")),
+		Site:        s.Find(".sitestr").First().Text(),
+		Points:      Int64Or(numExpr.ReplaceAllString(subtext.Find(".score").Text(), "$1"), 0),
+		Comments:    Int64Or(numExpr.ReplaceAllString(comments.Text(), "$1"), 0),
+		CommentsURL: comments.AttrOr("href", ""),
+		Submitter:   hnuserExpr.ReplaceAllString(subtext.Find(".hnuser").AttrOr("href", ""), ""),
+	}
+	if len(story.CommentsURL) == 0 && story.ID > 0 {
+		story.CommentsURL = fmt.Sprintf("item?id=%v", story.ID)
+	}
 	if len(story.CommentsURL) > 0 && !strings.HasPrefix(story.CommentsURL, "https://") {
 		story.CommentsURL = fmt.Sprintf("%s/%s", BaseURL, story.CommentsURL)
 	}
 
-	humanTime := s.Next().Find(".age").Text()
-	// For items favorited in the early days of the feature, HN spits out
-	// "on <date>" instead of a human-style delta.
-	switch strings.Split(humanTime, " ")[0] {
-	case "on":
-		humanTime = strings.Replace(humanTime, "Sept", "Sep", -1)
-		pieces := strings.Split(humanTime, " ")
-		if len(pieces) > 1 {
-			if ts, err := dateparse.ParseAny(strings.Join(pieces[1:], " ")); err == nil {
-				story.Timestamp = ts
-			}
-		}
-	default:
-		now := time.Now()
-		if ts := ago.Time(humanTime, now); ts != nil {
-			story.Timestamp = *ts
-		}
-	}
+	story.Timestamp = extractTimestamp(subtext.Find(".age").First())
+
+	// Markers such as "[dupe]" follow the title link.
+	markers := s.Find("td.title").Last().Text()
+	story.Dead = strings.Contains(markers, "[dead]")
+	story.Flagged = strings.Contains(markers, "[flagged]")
+	story.Dupe = strings.Contains(markers, "[dupe]")
+	story.Hidden = subtext.Find(`a[href^="hide?"]`).FilterFunction(func(_ int, a *goquery.Selection) bool {
+		return strings.Contains(a.AttrOr("href", ""), "un=t")
+	}).Length() > 0
+
+	story.Kind = storyKind(s, story)
 
 	return story
 }
+
+// storyKindPrefixes maps title prefixes to the kind of story they denote.
+var storyKindPrefixes = []struct {
+	prefix string
+	kind   domain.StoryKind
+}{
+	{"Ask HN:", domain.AskStory},
+	{"Show HN:", domain.ShowStory},
+	{"Launch HN:", domain.LaunchStory},
+	{"Tell HN:", domain.TellStory},
+	{"Poll:", domain.PollStory},
+}
+
+// storyKind classifies a story by its subtext, title and, on item pages,
+// the presence of poll options.
+func storyKind(s *goquery.Selection, story domain.Story) domain.StoryKind {
+	if story.Submitter == "" && s.Next().Find(".score").Length() == 0 {
+		return domain.JobStory
+	}
+	if s.ParentsFiltered(".fatitem").Find(".pollopt").Length() > 0 {
+		return domain.PollStory
+	}
+	return titleKind(story.Title)
+}
+
+// titleKind classifies a non-job, non-poll story by its title prefix.
+func titleKind(title string) domain.StoryKind {
+	for _, p := range storyKindPrefixes {
+		if strings.HasPrefix(strings.ToLower(title), strings.ToLower(p.prefix)) {
+			return p.kind
+		}
+	}
+	return domain.LinkStory
+}
@@ -0,0 +1,50 @@
+package common
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/PuerkitoBio/goquery"
+
+	"github.com/jaytaylor/hn-utils/domain"
+)
+
+func TestExtractStory(t *testing.T) {
+	doc, err := goquery.NewDocumentFromReader(strings.NewReader(storyListingHTML))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	testCases := []domain.Story{
+		{ID: 101, Rank: 1, Kind: domain.LinkStory, Title: "A link", Site: "example.com", Points: 120, Comments: 45, Submitter: "alice", Dupe: true},
+		{ID: 102, Rank: 2, Kind: domain.JobStory, Title: "Acme (YC S19) is hiring", Site: "acme.com"},
+		{ID: 103, Rank: 3, Kind: domain.AskStory, Title: "Ask HN: Anything?", Points: 2, Submitter: "bob", Hidden: true},
+		{ID: 104, Rank: 4, Kind: domain.ShowStory, Title: "Show HN: Old markup", Site: "example.org", Points: 7, Comments: 1, Submitter: "carol", Dead: true},
+	}
+
+	rows := doc.Find(".athing")
+	if expected, actual := len(testCases), rows.Length(); actual != expected {
+		t.Fatalf("Expected rows.Length()=%v but actual=%v", expected, actual)
+	}
+	for i, expected := range testCases {
+		actual := ExtractStory(rows.Eq(i))
+		actual.URL, actual.CommentsURL, actual.Timestamp = "", "", expected.Timestamp
+		if !reflect.DeepEqual(actual, expected) {
+			t.Errorf("[i=%v] Expected story=%+v but actual=%+v", i, expected, actual)
+		}
+	}
+}
+
+const storyListingHTML = `
+<html><body><table class="itemlist">
+  <tr class="athing" id="101"><td align="right" valign="top" class="title"><span class="rank">1.</span></td><td class="votelinks"></td><td class="title"><span class="titleline"><a href="https://example.com/a">A link</a><span class="sitebit comhead"> (<a href="from?site=example.com"><span class="sitestr">example.com</span></a>)</span></span> [dupe]</td></tr>
+  <tr><td colspan="2"></td><td class="subtext"><span class="subline"><span class="score" id="score_101">120 points</span> by <a href="user?id=alice" class="hnuser">alice</a> <span class="age" title="2019-01-15T19:45:00 1547581500"><a href="item?id=101">2 hours ago</a></span> | <a href="hide?id=101&amp;goto=news">hide</a> | <a href="item?id=101">45&nbsp;comments</a></span></td></tr>
+  <tr class="athing" id="102"><td align="right" valign="top" class="title"><span class="rank">2.</span></td><td></td><td class="title"><span class="titleline"><a href="https://acme.com/jobs">Acme (YC S19) is hiring</a><span class="sitebit comhead"> (<a href="from?site=acme.com"><span class="sitestr">acme.com</span></a>)</span></span></td></tr>
+  <tr><td colspan="2"></td><td class="subtext"><span class="age" title="2019-01-15T19:45:00 1547581500"><a href="item?id=102">3 hours ago</a></span> | <a href="hide?id=102&amp;goto=news">hide</a></td></tr>
+  <tr class="athing" id="103"><td align="right" valign="top" class="title"><span class="rank">3.</span></td><td class="votelinks"></td><td class="title"><span class="titleline"><a href="item?id=103">Ask HN: Anything?</a></span></td></tr>
+  <tr><td colspan="2"></td><td class="subtext"><span class="subline"><span class="score" id="score_103">2 points</span> by <a href="user?id=bob" class="hnuser">bob</a> <span class="age" title="2019-01-15T19:45:00 1547581500"><a href="item?id=103">1 hour ago</a></span> | <a href="hide?id=103&amp;un=t&amp;goto=hidden">un-hide</a> | <a href="item?id=103">discuss</a></span></td></tr>
+  <tr class="athing" id="104"><td align="right" valign="top" class="title"><span class="rank">4.</span></td><td class="votelinks"></td><td class="title"><a href="https://example.org/" class="storylink">Show HN: Old markup</a><span class="sitebit comhead"> (<a href="from?site=example.org"><span class="sitestr">example.org</span></a>)</span> [dead]</td></tr>
+  <tr><td colspan="2"></td><td class="subtext"><span class="score" id="score_104">7 points</span> by <a href="user?id=carol" class="hnuser">carol</a> <span class="age"><a href="item?id=104">on Jan 15, 2019</a></span> | <a href="item?id=104">1&nbsp;comment</a></td></tr>
+</table></body></html>
+`
@@ -0,0 +1,95 @@
+package common
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/araddon/dateparse"
+	"jaytaylor.com/html2text"
+
+	"github.com/jaytaylor/hn-utils/domain"
+)
+
+// ErrNoSuchUser is returned by ExtractUser when the page doesn't hold a user
+// profile.
+var ErrNoSuchUser = errors.New("no such user")
+
+// ExtractUser consumes an HN "/user?id=xxx" page DOM and parses out the
+// user's profile.  The links to the user's listings are built against
+// baseURL, the site the page was retrieved from (e.g. BaseURL).
+//
+// If you have a *goquery.Document, simply pass it in via: doc.Selection.
+func ExtractUser(doc *goquery.Selection, baseURL string) (domain.User, error) {
+	var user domain.User
+
+	// Profile fields are laid out as rows of "label:" and value cells.
+	doc.Find("tr").Each(func(_ int, row *goquery.Selection) {
+		cells := row.ChildrenFiltered("td")
+		if cells.Length() != 2 {
+			return
+		}
+		value := cells.Last()
+
+		switch strings.TrimSpace(cells.First().Text()) {
+		case "user:":
+			user.ID = strings.TrimSpace(value.Find(".hnuser").First().Text())
+			if user.ID == "" {
+				user.ID = strings.TrimSpace(value.Text())
+			}
+			if unix := Int64Or(value.AttrOr("timestamp", ""), -1); unix > 0 {
+				user.Created = time.Unix(unix, 0).UTC()
+			}
+
+		case "created:":
+			if !user.Created.IsZero() {
+				return
+			}
+			if ts, ok := parseCreated(value); ok {
+				user.Created = ts
+			}
+
+		case "karma:":
+			user.Karma = Int64Or(strings.TrimSpace(value.Text()), 0)
+
+		case "about:":
+			html, err := value.Html()
+			if err == nil {
+				user.AboutHTML = strings.TrimSpace(html)
+			}
+			if user.AboutHTML != "" {
+				user.About, _ = html2text.FromHTMLNode(value.Get(0))
+			}
+		}
+	})
+
+	if user.ID == "" {
+		return domain.User{}, ErrNoSuchUser
+	}
+
+	id := url.QueryEscape(user.ID)
+	user.SubmissionsURL = fmt.Sprintf("%v/submitted?id=%v", baseURL, id)
+	user.CommentsURL = fmt.Sprintf("%v/threads?id=%v", baseURL, id)
+	user.FavoritesURL = fmt.Sprintf("%v/favorites?id=%v", baseURL, id)
+	return user, nil
+}
+
+// parseCreated parses the "created:" cell, preferring the exact day in the
+// link to the front page of that day over the human-readable date.
+func parseCreated(value *goquery.Selection) (time.Time, bool) {
+	if href, ok := value.Find("a").First().Attr("href"); ok {
+		if u, err := url.Parse(href); err == nil {
+			if ts, err := time.ParseInLocation("2006-01-02", u.Query().Get("day"), time.UTC); err == nil {
+				return ts, true
+			}
+		}
+	}
+	ts, err := dateparse.ParseAny(strings.TrimSpace(value.Text()))
+	if err != nil {
+		return time.Time{}, false
+	}
+	return ts, true
+}
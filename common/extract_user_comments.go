@@ -0,0 +1,64 @@
+package common
+
+import (
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+
+	"github.com/jaytaylor/hn-utils/domain"
+)
+
+// ExtractUserComments consumes a page of an HN "/threads?id=xxx" comment
+// history and returns the user's comments, i.e. the top-level ones, along with
+// the story they were made on and their visible replies.  Problems which
+// didn't prevent parsing are returned as warnings.
+//
+// If you have a *goquery.Document, simply pass it in via: doc.Selection.
+func ExtractUserComments(doc *goquery.Selection) ([]domain.UserComment, []ParseWarning) {
+	// Reply context is only linked from the comment header.
+	type replyContext struct {
+		parentID   int64
+		storyID    int64
+		storyTitle string
+	}
+	contexts := map[int64]replyContext{}
+	doc.Find(".athing.comtr").Each(func(_ int, s *goquery.Selection) {
+		var (
+			parent = s.Find(".navs a, .par a").FilterFunction(func(_ int, a *goquery.Selection) bool {
+				return strings.TrimSpace(a.Text()) == "parent"
+			}).First()
+			story = s.Find(".onstory a").First()
+		)
+		contexts[Int64Or(s.AttrOr("id", "0"), -1)] = replyContext{
+			parentID:   itemID(parent.AttrOr("href", "")),
+			storyID:    itemID(story.AttrOr("href", "")),
+			storyTitle: story.AttrOr("title", strings.TrimSpace(story.Text())),
+		}
+	})
+
+	threads, warnings, _ := ParseDiscussion(doc, true)
+
+	comments := make([]domain.UserComment, 0, len(threads))
+	for _, c := range threads {
+		rc := contexts[c.ID]
+		comments = append(comments, domain.UserComment{
+			Comment:    *c,
+			ParentID:   rc.parentID,
+			StoryID:    rc.storyID,
+			StoryTitle: rc.storyTitle,
+		})
+	}
+	return comments, warnings
+}
+
+// itemID returns the ID in an "item?id=xxx" link, or 0.
+func itemID(href string) int64 {
+	if i := strings.Index(href, "item?id="); i >= 0 {
+		id := href[i+len("item?id="):]
+		if j := strings.IndexAny(id, "&#"); j >= 0 {
+			id = id[:j]
+		}
+		return Int64Or(id, 0)
+	}
+	return 0
+}
@@ -0,0 +1,118 @@
+package common
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+// threadsPage renders a minimal HN "/threads" page holding comments by pg
+// (top-level) with a reply by bob to each, and an optional "More" link.
+func threadsPage(more string, ids ...int64) string {
+	html := `<html><body><table class="comment-tree">`
+	for _, id := range ids {
+		html += fmt.Sprintf(`<tr class="athing comtr" id="%v"><td><table><tr><td class="ind" indent="0"></td><td class="default"><span class="comhead"><a href="user?id=pg" class="hnuser">pg</a> <span class="age" title="2019-01-15T19:45:00"><a href="item?id=%v">1 day ago</a></span> <span class="navs"> | <a href="item?id=%v">parent</a> | <a href="#%v">next</a> <span class="onstory"> | on: <a href="item?id=%v" title="Story %v">Story %v</a></span></span> <a class="togg" n="2"></a></span><div class="comment"><span class="commtext c00">Comment %v</span></div></td></tr></table></td></tr>`, id, id, id+1000, id+1, id+2000, id, id, id)
+		html += fmt.Sprintf(`<tr class="athing comtr" id="%v"><td><table><tr><td class="ind" indent="1"></td><td class="default"><span class="comhead"><a href="user?id=bob" class="hnuser">bob</a> <a class="togg" n="1"></a></span><div class="comment"><span class="commtext c00">Reply to %v</span></div></td></tr></table></td></tr>`, id+3000, id)
+	}
+	html += `</table>`
+	if more != "" {
+		html += fmt.Sprintf(`<a href="%v" class="morelink" rel="next">More</a>`, more)
+	}
+	return html + "</body></html>"
+}
+
+func TestExtractUserComments(t *testing.T) {
+	doc, err := goquery.NewDocumentFromReader(strings.NewReader(threadsPage("", 1, 2)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	comments, warnings := ExtractUserComments(doc.Selection)
+	if len(warnings) > 0 {
+		t.Errorf("Expected no warnings but actual=%v", warnings)
+	}
+
+	if expected, actual := 2, len(comments); actual != expected {
+		t.Fatalf("Expected len(comments)=%v but actual=%v", expected, actual)
+	}
+	c := comments[1]
+	if expected, actual := int64(2), c.ID; actual != expected {
+		t.Errorf("Expected c.ID=%v but actual=%v", expected, actual)
+	}
+	if expected, actual := int64(1002), c.ParentID; actual != expected {
+		t.Errorf("Expected c.ParentID=%v but actual=%v", expected, actual)
+	}
+	if expected, actual := int64(2002), c.StoryID; actual != expected {
+		t.Errorf("Expected c.StoryID=%v but actual=%v", expected, actual)
+	}
+	if expected, actual := "Story 2", c.StoryTitle; actual != expected {
+		t.Errorf("Expected c.StoryTitle=%q but actual=%q", expected, actual)
+	}
+	if expected, actual := 1, len(c.Children); actual != expected {
+		t.Fatalf("Expected len(c.Children)=%v but actual=%v", expected, actual)
+	}
+	if expected, actual := "bob", c.Children[0].Author; actual != expected {
+		t.Errorf("Expected c.Children[0].Author=%q but actual=%q", expected, actual)
+	}
+}
+
+func TestCrawlerUserComments(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/threads", func(w http.ResponseWriter, req *http.Request) {
+		switch req.URL.Query().Get("next") {
+		case "":
+			fmt.Fprint(w, threadsPage("threads?id=pg&next=4", 6, 5))
+		case "4":
+			fmt.Fprint(w, threadsPage("", 4, 3))
+		default:
+			t.Errorf("Unexpected page request: %v", req.URL)
+		}
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	testCases := []struct {
+		crawler          Crawler
+		expectedIDs      []int64
+		expectedCaughtUp bool
+	}{
+		{
+			crawler:     Crawler{},
+			expectedIDs: []int64{6, 5, 4, 3},
+		},
+		{
+			crawler:          Crawler{UntilID: 4},
+			expectedIDs:      []int64{6, 5},
+			expectedCaughtUp: true,
+		},
+		{
+			crawler:     Crawler{MaxItems: 3},
+			expectedIDs: []int64{6, 5, 4},
+		},
+	}
+
+	for i, testCase := range testCases {
+		crawler := testCase.crawler
+		crawler.Client = server.Client()
+		crawler.URL = server.URL + "/threads?id=pg"
+
+		comments, caughtUp, err := crawler.UserComments(context.Background())
+		if err != nil {
+			t.Fatalf("[i=%v] %s", i, err)
+		}
+		ids := []int64{}
+		for _, comment := range comments {
+			ids = append(ids, comment.ID)
+		}
+		if expected, actual := fmt.Sprint(testCase.expectedIDs), fmt.Sprint(ids); actual != expected {
+			t.Errorf("[i=%v] Expected IDs=%v but actual=%v", i, expected, actual)
+		}
+		if expected, actual := testCase.expectedCaughtUp, caughtUp; actual != expected {
+			t.Errorf("[i=%v] Expected caughtUp=%v but actual=%v", i, expected, actual)
+		}
+	}
+}
@@ -0,0 +1,56 @@
+package common
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+func TestExtractUser(t *testing.T) {
+	doc, err := goquery.NewDocumentFromReader(strings.NewReader(userHTML))
+	if err != nil {
+		t.Fatal(err)
+	}
+	user, err := ExtractUser(doc.Selection, "https://hn.example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if expected, actual := "pg", user.ID; actual != expected {
+		t.Errorf("Expected user.ID=%q but actual=%q", expected, actual)
+	}
+	if expected, actual := time.Date(2006, 10, 9, 0, 0, 0, 0, time.UTC), user.Created; !actual.Equal(expected) {
+		t.Errorf("Expected user.Created=%v but actual=%v", expected, actual)
+	}
+	if expected, actual := int64(157316), user.Karma; actual != expected {
+		t.Errorf("Expected user.Karma=%v but actual=%v", expected, actual)
+	}
+	if expected, actual := "Bug fixer.\n\nSee https://paulgraham.com", user.About; actual != expected {
+		t.Errorf("Expected user.About=%q but actual=%q", expected, actual)
+	}
+	if expected, actual := "https://hn.example.com/threads?id=pg", user.CommentsURL; actual != expected {
+		t.Errorf("Expected user.CommentsURL=%q but actual=%q", expected, actual)
+	}
+
+	doc, err = goquery.NewDocumentFromReader(strings.NewReader(`<html><body>No such user.</body></html>`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ExtractUser(doc.Selection, BaseURL); err != ErrNoSuchUser {
+		t.Errorf("Expected err=%v but actual=%v", ErrNoSuchUser, err)
+	}
+}
+
+const userHTML = `
+<html op="user"><body><center><table id="hnmain"><tr><td><table border="0">
+  <tr class="athing"><td valign="top">user:</td><td><a href="user?id=pg" class="hnuser">pg</a></td></tr>
+  <tr><td valign="top">created:</td><td><a href="front?day=2006-10-09&amp;birth=pg">October 9, 2006</a></td></tr>
+  <tr><td valign="top">karma:</td><td>157316</td></tr>
+  <tr><td valign="top">about:</td><td style="overflow:hidden;">Bug fixer.<p>See <a href="https://paulgraham.com" rel="nofollow">https://paulgraham.com</a></td></tr>
+  <tr><td></td><td><a href="submitted?id=pg"><u>submissions</u></a></td></tr>
+  <tr><td></td><td><a href="threads?id=pg"><u>comments</u></a></td></tr>
+  <tr><td></td><td><a href="favorites?id=pg"><u>favorites</u></a></td></tr>
+</table></td></tr></table></center></body></html>
+`
@@ -0,0 +1,101 @@
+package common
+
+import (
+	"context"
+	"sync"
+
+	"github.com/jaytaylor/hn-utils/domain"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// DefaultDiscussionWorkers is the number of discussions FetchDiscussions
+// fetches concurrently when no worker count is given.
+const DefaultDiscussionWorkers = 4
+
+// FetchDiscussions fetches the item page (see FetchItem) of each story through
+// a pool of workers and attaches the discussion tree to the story in place,
+// along with the text body and poll options.  Dead, flagged and deleted
+// comments are handled according to states.  Requests still pass through get,
+// so the throttle of its transport applies across all workers.
+//
+// A story whose discussion can't be fetched is left as it was.  Such failures
+// don't stop the other fetches and are returned keyed by story ID, as are the
+// parse warnings of the discussions which were fetched.  Once ctx is canceled
+// the remaining stories are skipped.
+func FetchDiscussions(ctx context.Context, get GetFunc, stories domain.Stories, workers int, states CommentStatePolicy) (failures map[int64]error, warnings map[int64][]ParseWarning) {
+	if workers < 1 {
+		workers = DefaultDiscussionWorkers
+	}
+
+	var (
+		indexes = make(chan int)
+		mu      sync.Mutex
+		wg      sync.WaitGroup
+	)
+	failures = map[int64]error{}
+	warnings = map[int64][]ParseWarning{}
+
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range indexes {
+				discussion := NewDiscussion()
+				discussion.Lenient = true
+				discussion.States = states
+				item, err := discussion.Fetch(ctx, get, stories[i].CommentsURL)
+				itemWarnings := discussion.Warnings
+				if err != nil {
+					mu.Lock()
+					failures[stories[i].ID] = err
+					mu.Unlock()
+					continue
+				}
+				if len(itemWarnings) > 0 {
+					mu.Lock()
+					warnings[stories[i].ID] = itemWarnings
+					mu.Unlock()
+				}
+				stories[i].Children = item.Children
+				if stories[i].Text == "" {
+					stories[i].Text, stories[i].TextHTML = item.Text, item.TextHTML
+				}
+				if item.PollOptions != nil {
+					stories[i].PollOptions = item.PollOptions
+				}
+			}
+		}()
+	}
+
+	for i := range stories {
+		if ctx.Err() != nil {
+			mu.Lock()
+			failures[stories[i].ID] = ctx.Err()
+			mu.Unlock()
+			continue
+		}
+		indexes <- i
+	}
+	close(indexes)
+	wg.Wait()
+
+	return
+}
+
+// FetchDiscussionsLogged is FetchDiscussions for command-line tools: parse
+// warnings and failures are logged rather than returned.
+func FetchDiscussionsLogged(ctx context.Context, get GetFunc, stories domain.Stories, workers int, states CommentStatePolicy) {
+	failures, warnings := FetchDiscussions(ctx, get, stories, workers, states)
+	for id, storyWarnings := range warnings {
+		for _, warning := range storyWarnings {
+			log.WithField("story-id", id).Warnf("Discussion parse: %s", warning)
+		}
+	}
+	for id, err := range failures {
+		log.WithField("story-id", id).Warnf("Fetching discussion failed: %s", err)
+	}
+	if len(failures) > 0 {
+		log.Warnf("Fetching %v of %v discussions failed", len(failures), len(stories))
+	}
+}
@@ -0,0 +1,111 @@
+package common
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/PuerkitoBio/goquery"
+
+	"github.com/jaytaylor/hn-utils/domain"
+)
+
+func TestFetchDiscussions(t *testing.T) {
+	const workers = 3
+
+	var (
+		mu          sync.Mutex
+		active      int
+		maxActive   int
+		failStoryID = "5"
+		warnStoryID = "7"
+	)
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/item", func(w http.ResponseWriter, req *http.Request) {
+		mu.Lock()
+		active++
+		if active > maxActive {
+			maxActive = active
+		}
+		mu.Unlock()
+		defer func() {
+			mu.Lock()
+			active--
+			mu.Unlock()
+		}()
+		time.Sleep(10 * time.Millisecond)
+
+		if req.URL.Query().Get("id") == failStoryID {
+			http.Error(w, "boom", http.StatusInternalServerError)
+			return
+		}
+		if req.URL.Query().Get("id") == warnStoryID {
+			// Orphaned reply, re-parented with a warning.
+			fmt.Fprint(w, discussionPage("", [2]int{100, 0}, [2]int{101, 80}))
+			return
+		}
+		fmt.Fprint(w, discussionPage("", [2]int{100, 0}, [2]int{101, 40}))
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	stories := domain.Stories{}
+	for id := int64(1); id <= 10; id++ {
+		stories = append(stories, domain.Story{ID: id, CommentsURL: fmt.Sprintf("%v/item?id=%v", server.URL, id)})
+	}
+
+	get := func(ctx context.Context, page string) (*goquery.Document, error) {
+		return GetDocument(ctx, server.Client(), page)
+	}
+	failures, warnings := FetchDiscussions(context.Background(), get, stories, workers, MarkCommentStates)
+
+	if expected, actual := 1, len(failures); actual != expected {
+		t.Fatalf("Expected len(failures)=%v but actual=%v", expected, actual)
+	}
+	if _, ok := failures[5]; !ok {
+		t.Errorf("Expected failure for story 5 but actual=%v", failures)
+	}
+	if expected, actual := 1, len(warnings); actual != expected {
+		t.Fatalf("Expected len(warnings)=%v but actual=%v", expected, actual)
+	}
+	if expected, actual := 1, len(warnings[7]); actual != expected {
+		t.Errorf("Expected len(warnings[7])=%v but actual=%v", expected, actual)
+	}
+	for _, story := range stories {
+		expected := 2
+		if story.ID == 5 {
+			expected = 0
+		}
+		if actual := story.Children.Len(); actual != expected {
+			t.Errorf("Expected story %v Children.Len()=%v but actual=%v", story.ID, expected, actual)
+		}
+	}
+	if maxActive > workers {
+		t.Errorf("Expected at most %v concurrent requests but actual=%v", workers, maxActive)
+	}
+}
+
+func TestFetchDiscussionsCommentStates(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		fmt.Fprint(w, commentStatesHTML)
+	}))
+	defer server.Close()
+
+	stories := domain.Stories{{ID: 1, CommentsURL: server.URL + "/item?id=1"}}
+	get := func(ctx context.Context, page string) (*goquery.Document, error) {
+		return GetDocument(ctx, server.Client(), page)
+	}
+	failures, _ := FetchDiscussions(context.Background(), get, stories, 1, DropCommentStates)
+
+	if len(failures) > 0 {
+		t.Fatalf("Unexpected failures: %v", failures)
+	}
+	if expected, actual := 3, stories[0].Children.Len(); actual != expected {
+		t.Errorf("Expected Children.Len()=%v but actual=%v", expected, actual)
+	}
+}
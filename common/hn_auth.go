@@ -1,6 +1,9 @@
 package common
 
 import (
+	"bytes"
+	"context"
+	"errors"
 	"fmt"
 	"io"
 	"io/ioutil"
@@ -8,6 +11,7 @@ import (
 	"net/http/cookiejar"
 	"net/url"
 	"strings"
+	"sync"
 )
 
 var (
@@ -15,53 +19,157 @@ var (
 	BaseURL = "https://news.ycombinator.com"
 
 	UserAgent = "Mozilla/5.0 (Macintosh; Intel Mac OS X 10_9_5) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/65.0.3325.162 Safari/537.36"
+
+	// Transport is used by clients created via NoAuthClient and Login.  Set it
+	// to a *Throttle to rate-limit and retry requests.  Nil means
+	// http.DefaultTransport.
+	Transport http.RoundTripper
 )
 
+var (
+	// ErrBadLogin is returned when HN rejects the username or password.
+	ErrBadLogin = errors.New("bad login")
+
+	// ErrValidationRequired is returned when HN demands a captcha or other
+	// validation before accepting the login.
+	ErrValidationRequired = errors.New("login validation (captcha) required")
+
+	// ErrSessionExpired is returned when HN serves a logged-out page to an
+	// authenticated session and logging in again did not help.
+	ErrSessionExpired = errors.New("session expired")
+)
+
+// SessionCookie is the name of the cookie holding an HN login session.
+const SessionCookie = "user"
+
 // Login returns an authenticated *http.Client (or errors out).
-func Login(username string, password string) (*http.Client, error) {
+func Login(ctx context.Context, username string, password string) (*http.Client, error) {
+	client := NoAuthClient()
+	if err := Authenticate(ctx, client, BaseURL, username, password); err != nil {
+		return nil, err
+	}
+	return client, nil
+}
+
+// Authenticate logs in to the HN instance at baseURL and installs a cookie jar
+// holding the resulting session into the passed client (see installJar).
+func Authenticate(ctx context.Context, client *http.Client, baseURL string, username string, password string) error {
 	jar, err := cookiejar.New(nil)
 	if err != nil {
-		return nil, fmt.Errorf("creating cookie jar: %s", err)
+		return fmt.Errorf("creating cookie jar: %s", err)
 	}
 
-	client := NoAuthClient()
-	client.Jar = jar
+	// Login success is signaled by a redirect, so don't follow it.
+	noRedirect := *client
+	noRedirect.Jar = jar
+	noRedirect.CheckRedirect = func(_ *http.Request, _ []*http.Request) error {
+		return http.ErrUseLastResponse
+	}
 
 	form := url.Values{}
 	form.Add("acct", username)
 	form.Add("pw", password)
 	form.Add("goto", "news")
 
-	req, err := http.NewRequest("POST", fmt.Sprintf("%s/login", BaseURL), strings.NewReader(form.Encode()))
+	req, err := http.NewRequestWithContext(ctx, "POST", fmt.Sprintf("%s/login", baseURL), strings.NewReader(form.Encode()))
 	if err != nil {
-		return nil, fmt.Errorf("login: creating POST request: %s", err)
+		return fmt.Errorf("login: creating POST request: %s", err)
 	}
 
 	req.Header.Add("Content-Type", "application/x-www-form-urlencoded")
-	req.Header.Add("Referer", BaseURL+"/")
-	req.Header.Add("Origin", BaseURL+"/")
+	req.Header.Add("Referer", baseURL+"/")
+	req.Header.Add("Origin", baseURL+"/")
 	req.Header.Add("Accept-Language", "en-US,en;q=0.9")
 	req.Header.Add("Upgrade-Insecure-Requests", "1")
 	req.Header.Add("Accept", "text/html,application/xhtml+xml,application/xml;q=0.9,image/webp,image/apng,*/*;q=0.8")
 	req.Header.Add("Cache-Control", "max-age=0")
-	req.Header.Add("Authority", strings.Split(BaseURL, "://")[1])
+	req.Header.Add("Authority", strings.TrimPrefix(strings.TrimPrefix(baseURL, "https://"), "http://"))
 	req.Header.Add("User-Agent", UserAgent)
 
-	resp, err := client.Do(req)
+	resp, err := noRedirect.Do(req)
 	if err != nil {
-		return nil, fmt.Errorf("login: %s", err)
+		return fmt.Errorf("login: %s", err)
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode/100 != 3 || resp.Header.Get("Location") == "" {
 		body, _ := ioutil.ReadAll(resp.Body)
-		return nil, fmt.Errorf("login: expected 3xx reponse status-code but got %v (body=%v)", resp.StatusCode, string(body))
+		switch {
+		case bytes.Contains(body, []byte("Bad login")):
+			return fmt.Errorf("login: %w", ErrBadLogin)
+		case bytes.Contains(body, []byte("Validation required")), bytes.Contains(body, []byte("recaptcha")):
+			return fmt.Errorf("login: %w", ErrValidationRequired)
+		}
+		return fmt.Errorf("login: expected 3xx reponse status-code but got %v (body=%v)", resp.StatusCode, string(body))
 	}
-	return client, nil
+
+	// A redirect alone isn't proof of success; HN also redirects some failed
+	// attempts.  Only the presence of the session cookie is.
+	if !hasSessionCookie(jar, baseURL) {
+		return fmt.Errorf("login: no %q cookie received: %w", SessionCookie, ErrBadLogin)
+	}
+
+	installJar(client, jar)
+	return nil
+}
+
+// swapJar is a cookie jar whose underlying jar can be replaced while other
+// goroutines send requests through the client holding it.
+type swapJar struct {
+	mu  sync.RWMutex
+	jar http.CookieJar
+}
+
+func (j *swapJar) SetCookies(u *url.URL, cookies []*http.Cookie) {
+	j.mu.RLock()
+	defer j.mu.RUnlock()
+	j.jar.SetCookies(u, cookies)
+}
+
+func (j *swapJar) Cookies(u *url.URL) []*http.Cookie {
+	j.mu.RLock()
+	defer j.mu.RUnlock()
+	return j.jar.Cookies(u)
+}
+
+func (j *swapJar) swap(jar http.CookieJar) {
+	j.mu.Lock()
+	j.jar = jar
+	j.mu.Unlock()
+}
+
+// installJar makes client use jar.  Once a client has had a jar installed,
+// later ones are swapped in place rather than assigned to client.Jar, so
+// logging in again is safe while the client is in use.
+func installJar(client *http.Client, jar http.CookieJar) {
+	if current, ok := client.Jar.(*swapJar); ok {
+		current.swap(jar)
+		return
+	}
+	client.Jar = &swapJar{jar: jar}
+}
+
+// hasSessionCookie returns true when the jar holds an HN session cookie for
+// baseURL.
+func hasSessionCookie(jar http.CookieJar, baseURL string) bool {
+	if jar == nil {
+		return false
+	}
+	u, err := url.Parse(baseURL)
+	if err != nil {
+		return false
+	}
+	for _, cookie := range jar.Cookies(u) {
+		if cookie.Name == SessionCookie && cookie.Value != "" {
+			return true
+		}
+	}
+	return false
 }
 
 func NoAuthClient() *http.Client {
 	client := &http.Client{
+		Transport: Transport,
 		CheckRedirect: func(_ *http.Request, _ []*http.Request) error {
 			return http.ErrUseLastResponse
 		},
@@ -71,8 +179,8 @@ func NoAuthClient() *http.Client {
 
 // CheckedGet requires an already authenticated *http.Client and retrieves
 // content from the specified page.
-func CheckedGet(client *http.Client, page string) (io.ReadCloser, error) {
-	req, err := http.NewRequest("GET", page, nil)
+func CheckedGet(ctx context.Context, client *http.Client, page string) (io.ReadCloser, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", page, nil)
 	if err != nil {
 		return nil, fmt.Errorf("creating logged-in GET request: %s", err)
 	}
@@ -83,6 +191,7 @@ func CheckedGet(client *http.Client, page string) (io.ReadCloser, error) {
 	}
 
 	if resp.StatusCode/100 != 2 {
+		resp.Body.Close()
 		return nil, fmt.Errorf("expected 2xx response status-code from %v but got %v", page, resp.StatusCode)
 	}
 
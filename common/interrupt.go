@@ -0,0 +1,40 @@
+package common
+
+import (
+	"context"
+	"errors"
+	"os"
+	"os/signal"
+	"syscall"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// InterruptContext returns a context which is canceled upon the first SIGINT
+// or SIGTERM.  After that, default signal handling is restored so a second
+// signal terminates the process immediately.
+func InterruptContext(parent context.Context) (context.Context, context.CancelFunc) {
+	var (
+		ctx, cancel = context.WithCancel(parent)
+		ch          = make(chan os.Signal, 1)
+	)
+
+	signal.Notify(ch, os.Interrupt, syscall.SIGTERM)
+
+	go func() {
+		select {
+		case sig := <-ch:
+			log.WithField("signal", sig).Warn("Interrupted; aborting pending requests and keeping what was collected so far (interrupt again to exit immediately)")
+			cancel()
+		case <-ctx.Done():
+		}
+		signal.Stop(ch)
+	}()
+
+	return ctx, cancel
+}
+
+// IsInterrupted returns true when err is the result of context cancellation.
+func IsInterrupted(err error) bool {
+	return errors.Is(err, context.Canceled)
+}
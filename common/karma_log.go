@@ -0,0 +1,60 @@
+package common
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/jaytaylor/hn-utils/domain"
+)
+
+// AppendKarmaSnapshot appends snapshot to the named karma log, a file of
+// newline-delimited JSON KarmaSnapshot objects, creating it when necessary.
+func AppendKarmaSnapshot(filename string, snapshot domain.KarmaSnapshot) error {
+	bs, err := json.Marshal(snapshot)
+	if err != nil {
+		return err
+	}
+
+	file, err := os.OpenFile(filename, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0644)
+	if err != nil {
+		return fmt.Errorf("opening karma log %v: %s", filename, err)
+	}
+	if _, err := file.Write(append(bs, '\n')); err != nil {
+		file.Close()
+		return fmt.Errorf("writing karma log %v: %s", filename, err)
+	}
+	if err := file.Close(); err != nil {
+		return fmt.Errorf("closing karma log %v: %s", filename, err)
+	}
+	return nil
+}
+
+// LoadKarmaSnapshots reads all snapshots from the named karma log.
+func LoadKarmaSnapshots(filename string) ([]domain.KarmaSnapshot, error) {
+	file, err := os.Open(filename)
+	if err != nil {
+		return nil, fmt.Errorf("opening karma log %v: %s", filename, err)
+	}
+	defer file.Close()
+
+	var (
+		snapshots []domain.KarmaSnapshot
+		scanner   = bufio.NewScanner(file)
+	)
+	for line := 1; scanner.Scan(); line++ {
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+		var snapshot domain.KarmaSnapshot
+		if err := json.Unmarshal(scanner.Bytes(), &snapshot); err != nil {
+			return nil, fmt.Errorf("parsing karma log %v line %v: %s", filename, line, err)
+		}
+		snapshots = append(snapshots, snapshot)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("reading karma log %v: %s", filename, err)
+	}
+	return snapshots, nil
+}
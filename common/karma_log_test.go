@@ -0,0 +1,29 @@
+package common
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/jaytaylor/hn-utils/domain"
+)
+
+func TestKarmaLog(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "karma.jsonl")
+	for _, karma := range []int64{10, 12} {
+		if err := AppendKarmaSnapshot(filename, domain.KarmaSnapshot{User: "pg", Karma: karma, Timestamp: time.Now()}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	snapshots, err := LoadKarmaSnapshots(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected, actual := 2, len(snapshots); actual != expected {
+		t.Fatalf("Expected len(snapshots)=%v but actual=%v", expected, actual)
+	}
+	if expected, actual := int64(12), snapshots[1].Karma; actual != expected {
+		t.Errorf("Expected snapshots[1].Karma=%v but actual=%v", expected, actual)
+	}
+}
@@ -1,10 +1,13 @@
 package common
 
 import (
+	"bufio"
+	"bytes"
 	"encoding/json"
 	"fmt"
 	"io"
 	"os"
+	"time"
 
 	"github.com/jaytaylor/hn-utils/domain"
 
@@ -13,18 +16,27 @@ import (
 
 // LoadStories loads a array of stories from the named file.
 // "-" can be used to signify readying from STDIN.
+//
+// Both the versioned domain.Database document and the legacy format (a bare
+// array of stories keyed by Go field names) are accepted.
 func LoadStories(filename string) (domain.Stories, error) {
-	var (
-		stories domain.Stories
-		r       io.Reader
-	)
+	db, err := LoadDatabase(filename)
+	if err != nil {
+		return nil, err
+	}
+	return db.Stories, nil
+}
+
+// LoadDatabase loads a database from the named file, like LoadStories.
+func LoadDatabase(filename string) (domain.Database, error) {
+	var r io.Reader
 
 	if filename == "-" {
 		r = os.Stdin
 	} else {
 		file, err := os.Open(filename)
 		if err != nil {
-			return nil, fmt.Errorf("opening %v: %s", filename, err)
+			return domain.Database{}, fmt.Errorf("opening %v: %s", filename, err)
 		}
 		defer func() {
 			if err := file.Close(); err != nil {
@@ -34,11 +46,146 @@ func LoadStories(filename string) (domain.Stories, error) {
 		r = file
 	}
 
-	dec := json.NewDecoder(r)
-	if err := dec.Decode(&stories); err != nil {
-		return nil, fmt.Errorf("loading stories from %v: %s", filename, err)
+	db, err := DecodeDatabase(r)
+	if err != nil {
+		return domain.Database{}, fmt.Errorf("loading %v: %s", filename, err)
+	}
+	log.Debugf("Loaded %v stories and %v comments from %v", len(db.Stories), len(db.Comments), filename)
+
+	return db, nil
+}
+
+// DecodeStories decodes stories in either the versioned or the legacy format.
+func DecodeStories(r io.Reader) (domain.Stories, error) {
+	db, err := DecodeDatabase(r)
+	if err != nil {
+		return nil, err
+	}
+	return db.Stories, nil
+}
+
+// DecodeDatabase decodes a database in either the versioned or the legacy
+// format.  Legacy data is migrated to the current schema version.
+func DecodeDatabase(r io.Reader) (domain.Database, error) {
+	br := bufio.NewReader(r)
+
+	first, err := firstNonSpace(br)
+	if err != nil {
+		return domain.Database{}, err
 	}
-	log.Debugf("Loaded %v stories from %v", len(stories), filename)
 
-	return stories, nil
+	dec := json.NewDecoder(br)
+
+	if first == '[' {
+		var legacy []legacyStory
+		if err := dec.Decode(&legacy); err != nil {
+			return domain.Database{}, err
+		}
+		stories := make(domain.Stories, 0, len(legacy))
+		for _, story := range legacy {
+			stories = append(stories, story.migrate())
+		}
+		return domain.NewDatabase(stories), nil
+	}
+
+	var db domain.Database
+	if err := dec.Decode(&db); err != nil {
+		return domain.Database{}, err
+	}
+	if db.SchemaVersion > domain.SchemaVersion {
+		return domain.Database{}, fmt.Errorf("unsupported schema version %v (newest supported is %v)", db.SchemaVersion, domain.SchemaVersion)
+	}
+	if db.Stories == nil {
+		db.Stories = domain.Stories{}
+	}
+	return db, nil
+}
+
+// firstNonSpace peeks at the first non-whitespace byte without consuming it.
+func firstNonSpace(br *bufio.Reader) (byte, error) {
+	for {
+		b, err := br.ReadByte()
+		if err != nil {
+			return 0, err
+		}
+		if !bytes.ContainsRune([]byte(" \t\r\n"), rune(b)) {
+			return b, br.UnreadByte()
+		}
+	}
+}
+
+// legacyStory is the schema version 0 representation of domain.Story.
+type legacyStory struct {
+	ID          int64            `json:"ID"`
+	Title       string           `json:"Title"`
+	URL         string           `json:"URL"`
+	Points      int64            `json:"Points"`
+	Comments    int64            `json:"Comments"`
+	CommentsURL string           `json:"CommentsURL"`
+	Submitter   string           `json:"Submitter"`
+	Timestamp   time.Time        `json:"Timestamp"`
+	Children    []*legacyComment `json:"Children"`
+}
+
+// legacyComment is the schema version 0 representation of domain.Comment.
+type legacyComment struct {
+	ID        int64            `json:"ID"`
+	Author    string           `json:"Author"`
+	Timestamp time.Time        `json:"Timestamp"`
+	Content   string           `json:"Content"`
+	Width     int              `json:"Width"`
+	N         int              `json:"N"`
+	Children  []*legacyComment `json:"Children"`
+}
+
+// migrate converts a legacy story to the current schema.  Legacy scrapes
+// used -1 for a missing score or comment count; job postings were the stories
+// without a submitter or score.
+func (s legacyStory) migrate() domain.Story {
+	kind := titleKind(s.Title)
+	if s.Submitter == "" && s.Points < 0 {
+		kind = domain.JobStory
+	}
+	return domain.Story{
+		ID:          s.ID,
+		Title:       s.Title,
+		URL:         s.URL,
+		Kind:        kind,
+		Points:      nonNegative(s.Points),
+		Comments:    nonNegative(s.Comments),
+		CommentsURL: s.CommentsURL,
+		Submitter:   s.Submitter,
+		Timestamp:   s.Timestamp,
+		Children:    migrateComments(s.Children),
+	}
+}
+
+// nonNegative maps the legacy -1 "unknown" count to 0.
+func nonNegative(n int64) int64 {
+	if n < 0 {
+		return 0
+	}
+	return n
+}
+
+func migrateComments(legacy []*legacyComment) domain.Threads {
+	if legacy == nil {
+		return nil
+	}
+	threads := make(domain.Threads, 0, len(legacy))
+	for _, c := range legacy {
+		if c == nil {
+			continue
+		}
+		threads = append(threads, &domain.Comment{
+			ID:        c.ID,
+			Author:    c.Author,
+			Timestamp: c.Timestamp,
+			Content:   c.Content,
+			Width:     c.Width,
+			N:         c.N,
+			Children:  migrateComments(c.Children),
+		})
+	}
+	return threads
 }
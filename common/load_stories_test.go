@@ -0,0 +1,136 @@
+package common
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/jaytaylor/hn-utils/domain"
+)
+
+// legacyStoriesJSON was written back when Story.Children held a flat slice of
+// Comment values rather than the Threads tree.
+const legacyStoriesJSON = `[
+    {
+        "ID": 18914411,
+        "Title": "Brexit Deal Fails in Parliament",
+        "URL": "https://www.nytimes.com/2019/01/15/world/europe/brexit-vote.html",
+        "Points": 612,
+        "Comments": 3,
+        "CommentsURL": "https://news.ycombinator.com/item?id=18914411",
+        "Submitter": "tosh",
+        "Timestamp": "2019-01-15T19:45:00Z",
+        "Children": [
+            {
+                "ID": 18914500,
+                "Author": "alice",
+                "Timestamp": "2019-01-15T20:00:00Z",
+                "Content": "First top-level comment.",
+                "Width": 0,
+                "N": 2,
+                "Children": [
+                    {
+                        "ID": 18914600,
+                        "Author": "bob",
+                        "Timestamp": "2019-01-15T20:05:00Z",
+                        "Content": "A reply.",
+                        "Width": 40,
+                        "N": 1,
+                        "Children": null
+                    }
+                ]
+            }
+        ]
+    },
+    {
+        "ID": 18914412,
+        "Title": "Listing story without discussion",
+        "Children": null
+    },
+    {
+        "ID": 18914413,
+        "Title": "Acme (YC W19) Is Hiring Engineers",
+        "Points": -1,
+        "Comments": -1,
+        "Children": null
+    }
+]`
+
+func TestLoadStoriesLegacyChildren(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "stories.json")
+	if err := ioutil.WriteFile(filename, []byte(legacyStoriesJSON), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	stories, err := LoadStories(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected, actual := 3, len(stories); actual != expected {
+		t.Fatalf("Expected len(stories)=%v but actual=%v", expected, actual)
+	}
+	if expected, actual := 2, stories[0].Children.Len(); actual != expected {
+		t.Errorf("Expected stories[0].Children.Len()=%v but actual=%v", expected, actual)
+	}
+	if expected, actual := "bob", stories[0].Children[0].Children[0].Author; actual != expected {
+		t.Errorf("Expected nested reply author=%q but actual=%q", expected, actual)
+	}
+	if expected, actual := 0, stories[1].Children.Len(); actual != expected {
+		t.Errorf("Expected stories[1].Children.Len()=%v but actual=%v", expected, actual)
+	}
+	if expected, actual := domain.LinkStory, stories[0].Kind; actual != expected {
+		t.Errorf("Expected stories[0].Kind=%v but actual=%v", expected, actual)
+	}
+	if expected, actual := domain.JobStory, stories[2].Kind; actual != expected {
+		t.Errorf("Expected stories[2].Kind=%v but actual=%v", expected, actual)
+	}
+	if expected, actual := int64(0), stories[2].Points; actual != expected {
+		t.Errorf("Expected stories[2].Points=%v but actual=%v", expected, actual)
+	}
+	if expected, actual := int64(0), stories[2].Comments; actual != expected {
+		t.Errorf("Expected stories[2].Comments=%v but actual=%v", expected, actual)
+	}
+}
+
+func TestDecodeStoriesDatabase(t *testing.T) {
+	testCases := []struct {
+		input       string
+		expected    int
+		expectError bool
+	}{
+		{
+			input:    `{"schema_version": 1, "stories": [{"id": 1, "comments_url": "https://news.ycombinator.com/item?id=1", "children": [{"id": 2, "thread_size": 1}]}]}`,
+			expected: 1,
+		},
+		{
+			input:    `{"schema_version": 1, "stories": null}`,
+			expected: 0,
+		},
+		{
+			input:       `{"schema_version": 2, "stories": []}`,
+			expectError: true,
+		},
+		{
+			input:       `"stories"`,
+			expectError: true,
+		},
+	}
+
+	for i, testCase := range testCases {
+		stories, err := DecodeStories(strings.NewReader(testCase.input))
+		if testCase.expectError {
+			if err == nil {
+				t.Errorf("[i=%v] Expected error but got none", i)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("[i=%v] Unexpected error: %s", i, err)
+			continue
+		}
+		if expected, actual := testCase.expected, len(stories); actual != expected {
+			t.Errorf("[i=%v] Expected len(stories)=%v but actual=%v", i, expected, actual)
+		}
+	}
+}
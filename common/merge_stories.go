@@ -0,0 +1,102 @@
+package common
+
+import (
+	"github.com/jaytaylor/hn-utils/domain"
+)
+
+// KnownIDs returns the set of IDs of stories, for use as Crawler.Known.
+func KnownIDs(stories domain.Stories) map[int64]bool {
+	known := make(map[int64]bool, len(stories))
+	for _, story := range stories {
+		known[story.ID] = true
+	}
+	return known
+}
+
+// KnownCommentIDs returns the set of IDs of comments, for use as
+// Crawler.Known.
+func KnownCommentIDs(comments []domain.UserComment) map[int64]bool {
+	known := make(map[int64]bool, len(comments))
+	for _, comment := range comments {
+		known[comment.ID] = true
+	}
+	return known
+}
+
+// MergeStories front-loads fresh stories, in the order HN listed them, onto
+// existing ones.  Each story ID is kept once, at its first position.
+//
+// Existing stories which appear in fresh are moved up to their fresh position
+// and have their rank, points and comment count refreshed, while fields fresh
+// listings don't carry (e.g. a previously fetched discussion) are retained.
+// Being listed again also clears a story's RemovedAt.
+// The remaining existing stories have their rank cleared, as their current
+// position in the listing is unknown.
+func MergeStories(fresh domain.Stories, existing domain.Stories) domain.Stories {
+	previous := map[int64]domain.Story{}
+	for _, story := range existing {
+		if _, ok := previous[story.ID]; !ok {
+			previous[story.ID] = story
+		}
+	}
+
+	var (
+		merged = domain.Stories{}
+		seen   = map[int64]struct{}{}
+	)
+	for _, story := range fresh {
+		if _, ok := seen[story.ID]; ok {
+			continue
+		}
+		seen[story.ID] = struct{}{}
+		if p, ok := previous[story.ID]; ok {
+			story = refreshStory(p, story)
+		}
+		merged = append(merged, story)
+	}
+	for _, story := range existing {
+		if _, ok := seen[story.ID]; ok {
+			continue
+		}
+		seen[story.ID] = struct{}{}
+		story.Rank = 0
+		merged = append(merged, story)
+	}
+	return merged
+}
+
+// refreshStory returns a copy of the previous version of a story updated with
+// the counts of its current listing entry, and no longer marked as removed.  A
+// discussion fetched along with current replaces the previous one.
+func refreshStory(previous domain.Story, current domain.Story) domain.Story {
+	story := previous
+	story.RemovedAt = nil
+	story.Rank = current.Rank
+	story.Points = current.Points
+	story.Comments = current.Comments
+	if current.Children != nil {
+		story.Text, story.TextHTML = current.Text, current.TextHTML
+		story.PollOptions = current.PollOptions
+		story.Children = current.Children
+	}
+	return story
+}
+
+// MergeUserComments front-loads fresh comments onto existing ones.  Each
+// comment ID is kept once, with the fresh version taking precedence.
+func MergeUserComments(fresh []domain.UserComment, existing []domain.UserComment) []domain.UserComment {
+	var (
+		merged = []domain.UserComment{}
+		seen   = map[int64]struct{}{}
+	)
+	for _, comments := range [][]domain.UserComment{fresh, existing} {
+		for _, comment := range comments {
+			if _, ok := seen[comment.ID]; ok {
+				continue
+			}
+			seen[comment.ID] = struct{}{}
+			merged = append(merged, comment)
+		}
+	}
+	return merged
+}
@@ -0,0 +1,95 @@
+package common
+
+import (
+	"testing"
+	"time"
+
+	"github.com/jaytaylor/hn-utils/domain"
+)
+
+func TestMergeStories(t *testing.T) {
+	var (
+		removedAt = time.Date(2019, 1, 15, 0, 0, 0, 0, time.UTC)
+		existing  = domain.Stories{
+			{ID: 5, Rank: 1, Points: 10, Comments: 1, Title: "Five", Children: domain.Threads{{ID: 50}}},
+			{ID: 3, Rank: 2, Points: 30, Comments: 3, Title: "Three", RemovedAt: &removedAt},
+			{ID: 5, Rank: 3, Points: 10, Comments: 1, Title: "Five again"},
+			{ID: 1, Rank: 4, Points: 1, Comments: 0, Title: "One", RemovedAt: &removedAt},
+		}
+		fresh = domain.Stories{
+			{ID: 6, Rank: 1, Points: 60, Title: "Six"},
+			{ID: 3, Rank: 2, Points: 33, Comments: 4, Title: "Three"},
+			{ID: 4, Rank: 3, Points: 40, Title: "Four"},
+			{ID: 5, Rank: 4, Points: 12, Comments: 2, Title: "Five"},
+			{ID: 6, Rank: 5, Points: 60, Title: "Six"},
+		}
+		expected = []struct {
+			id       int64
+			rank     int
+			points   int64
+			comments int64
+			children int
+			removed  bool
+		}{
+			{id: 6, rank: 1, points: 60},
+			{id: 3, rank: 2, points: 33, comments: 4},
+			{id: 4, rank: 3, points: 40},
+			{id: 5, rank: 4, points: 12, comments: 2, children: 1},
+			{id: 1, points: 1, removed: true},
+		}
+	)
+
+	merged := MergeStories(fresh, existing)
+	if expected, actual := len(expected), len(merged); actual != expected {
+		t.Fatalf("Expected len(merged)=%v but actual=%v", expected, actual)
+	}
+	for i, story := range merged {
+		if expected, actual := expected[i].id, story.ID; actual != expected {
+			t.Errorf("[i=%v] Expected ID=%v but actual=%v", i, expected, actual)
+		}
+		if expected, actual := expected[i].rank, story.Rank; actual != expected {
+			t.Errorf("[i=%v] Expected Rank=%v but actual=%v", i, expected, actual)
+		}
+		if expected, actual := expected[i].points, story.Points; actual != expected {
+			t.Errorf("[i=%v] Expected Points=%v but actual=%v", i, expected, actual)
+		}
+		if expected, actual := expected[i].comments, story.Comments; actual != expected {
+			t.Errorf("[i=%v] Expected Comments=%v but actual=%v", i, expected, actual)
+		}
+		if expected, actual := expected[i].children, len(story.Children); actual != expected {
+			t.Errorf("[i=%v] Expected len(Children)=%v but actual=%v", i, expected, actual)
+		}
+		if expected, actual := expected[i].removed, story.RemovedAt != nil; actual != expected {
+			t.Errorf("[i=%v] Expected removed=%v but actual=%v", i, expected, actual)
+		}
+	}
+}
+
+func TestMergeUserComments(t *testing.T) {
+	var (
+		existing = []domain.UserComment{
+			{Comment: domain.Comment{ID: 3, Content: "old"}},
+			{Comment: domain.Comment{ID: 2}},
+			{Comment: domain.Comment{ID: 3, Content: "older"}},
+		}
+		fresh = []domain.UserComment{
+			{Comment: domain.Comment{ID: 4}},
+			{Comment: domain.Comment{ID: 3, Content: "edited"}},
+			{Comment: domain.Comment{ID: 4}},
+		}
+		expectedIDs = []int64{4, 3, 2}
+	)
+
+	merged := MergeUserComments(fresh, existing)
+	if expected, actual := len(expectedIDs), len(merged); actual != expected {
+		t.Fatalf("Expected len(merged)=%v but actual=%v", expected, actual)
+	}
+	for i, comment := range merged {
+		if expected, actual := expectedIDs[i], comment.ID; actual != expected {
+			t.Errorf("[i=%v] Expected ID=%v but actual=%v", i, expected, actual)
+		}
+	}
+	if expected, actual := "edited", merged[1].Content; actual != expected {
+		t.Errorf("Expected Content=%q but actual=%q", expected, actual)
+	}
+}
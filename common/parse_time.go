@@ -0,0 +1,19 @@
+package common
+
+import (
+	"fmt"
+	"time"
+)
+
+// ParseTime parses a day ("2006-01-02", taken as UTC) or an RFC 3339 time, as
+// accepted by the --since flags.
+func ParseTime(s string) (time.Time, error) {
+	if ts, err := time.Parse(FrontDayLayout, s); err == nil {
+		return ts, nil
+	}
+	ts, err := time.Parse(time.RFC3339, s)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("parsing time %q: must be YYYY-MM-DD or RFC 3339", s)
+	}
+	return ts, nil
+}
@@ -0,0 +1,46 @@
+package common
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseTime(t *testing.T) {
+	testCases := []struct {
+		str         string
+		expected    time.Time
+		expectedErr bool
+	}{
+		{
+			str:      "2019-01-16",
+			expected: time.Date(2019, 1, 16, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			str:      "2019-01-16T08:30:00Z",
+			expected: time.Date(2019, 1, 16, 8, 30, 0, 0, time.UTC),
+		},
+		{
+			str:      "2019-01-16T08:30:00-08:00",
+			expected: time.Date(2019, 1, 16, 16, 30, 0, 0, time.UTC),
+		},
+		{
+			str:         "2019/01/16",
+			expectedErr: true,
+		},
+		{
+			str:         "",
+			expectedErr: true,
+		},
+	}
+
+	for i, testCase := range testCases {
+		actual, err := ParseTime(testCase.str)
+		if expected, actual := testCase.expectedErr, err != nil; actual != expected {
+			t.Errorf("[i=%v] Expected err!=nil=%v but actual=%v (err=%v)", i, expected, actual, err)
+			continue
+		}
+		if !actual.Equal(testCase.expected) {
+			t.Errorf("[i=%v] Expected ParseTime(%q)=%v but actual=%v", i, testCase.str, testCase.expected, actual)
+		}
+	}
+}
@@ -0,0 +1,89 @@
+package common
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"strings"
+
+	"golang.org/x/term"
+)
+
+// PasswordEnvVar is the environment variable consulted for the HN password.
+const PasswordEnvVar = "HN_PASSWORD"
+
+// PasswordSources describes where to look for the HN password.  Resolve
+// consults them in the following order of precedence, returning the first
+// password found:
+//
+//  1. Flag: the value of the -p/--password flag.
+//  2. Command: a credential-helper command run through the shell, whose
+//     standard output is the password.  The username is passed in the
+//     HN_USER environment variable.
+//  3. File: a file whose contents are the password.
+//  4. The HN_PASSWORD environment variable.
+//  5. Prompt: an interactive prompt with echo disabled, when requested and
+//     STDIN is a terminal.
+//
+// Trailing newlines are trimmed from commands' output and files' contents.
+type PasswordSources struct {
+	Flag    string
+	Command string
+	File    string
+	Prompt  bool
+}
+
+// Resolve returns the password from the highest precedence source available,
+// or an empty string if there is none.
+func (src PasswordSources) Resolve(ctx context.Context, username string) (string, error) {
+	if src.Flag != "" {
+		return src.Flag, nil
+	}
+
+	if src.Command != "" {
+		var stdout, stderr bytes.Buffer
+		cmd := exec.CommandContext(ctx, "sh", "-c", src.Command)
+		cmd.Env = append(os.Environ(), "HN_USER="+username)
+		cmd.Stdout = &stdout
+		cmd.Stderr = &stderr
+		if err := cmd.Run(); err != nil {
+			return "", fmt.Errorf("running password command: %s (stderr=%q)", err, strings.TrimSpace(stderr.String()))
+		}
+		return trimNewline(stdout.String()), nil
+	}
+
+	if src.File != "" {
+		bs, err := ioutil.ReadFile(src.File)
+		if err != nil {
+			return "", fmt.Errorf("reading password file: %s", err)
+		}
+		return trimNewline(string(bs)), nil
+	}
+
+	if password := os.Getenv(PasswordEnvVar); password != "" {
+		return password, nil
+	}
+
+	if src.Prompt {
+		fd := int(os.Stdin.Fd())
+		if !term.IsTerminal(fd) {
+			return "", fmt.Errorf("cannot prompt for password: STDIN is not a terminal")
+		}
+		fmt.Fprintf(os.Stderr, "HN password for %v: ", username)
+		bs, err := term.ReadPassword(fd)
+		fmt.Fprintln(os.Stderr)
+		if err != nil {
+			return "", fmt.Errorf("reading password: %s", err)
+		}
+		return string(bs), nil
+	}
+
+	return "", nil
+}
+
+func trimNewline(s string) string {
+	return strings.TrimRight(s, "\r\n")
+}
@@ -0,0 +1,55 @@
+package common
+
+import (
+	"context"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+)
+
+func TestPasswordSourcesResolve(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "password")
+	if err := ioutil.WriteFile(file, []byte("from-file\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv(PasswordEnvVar, "from-env")
+
+	testCases := []struct {
+		sources  PasswordSources
+		expected string
+	}{
+		{
+			sources:  PasswordSources{Flag: "from-flag", Command: "echo from-command", File: file},
+			expected: "from-flag",
+		},
+		{
+			sources:  PasswordSources{Command: `echo "from-command-for-$HN_USER"`, File: file},
+			expected: "from-command-for-alice",
+		},
+		{
+			sources:  PasswordSources{File: file},
+			expected: "from-file",
+		},
+		{
+			sources:  PasswordSources{},
+			expected: "from-env",
+		},
+	}
+
+	for i, testCase := range testCases {
+		actual, err := testCase.sources.Resolve(context.Background(), "alice")
+		if err != nil {
+			t.Errorf("[i=%v] %s", i, err)
+			continue
+		}
+		if actual != testCase.expected {
+			t.Errorf("[i=%v] Expected password=%q but actual=%q", i, testCase.expected, actual)
+		}
+	}
+}
+
+func TestPasswordSourcesCommandFailure(t *testing.T) {
+	if _, err := (PasswordSources{Command: "exit 1"}).Resolve(context.Background(), "alice"); err == nil {
+		t.Fatal("Expected error from failing password command but got nil")
+	}
+}
@@ -0,0 +1,54 @@
+package common
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/jaytaylor/hn-utils/domain"
+)
+
+// PollTallies keeps only the polls among stories, fetching their item pages
+// for the current scores of the options.
+//
+// Listing pages only reveal polls titled "Poll: ..."; others (e.g. "Ask HN:
+// ...") look like any other self-post, so the item page of every self-post is
+// fetched and classified by its poll options.
+//
+// A story whose item page can't be fetched keeps its previous tally (and is
+// kept only if already known to be a poll).  Such failures don't stop the
+// other fetches and are returned keyed by story ID.  Once ctx is canceled the
+// remaining stories are skipped.
+func PollTallies(ctx context.Context, get GetFunc, stories domain.Stories) (polls domain.Stories, failures map[int64]error) {
+	polls = domain.Stories{}
+	failures = map[int64]error{}
+	for _, story := range stories {
+		if !mayBePoll(story) {
+			continue
+		}
+		if err := ctx.Err(); err != nil {
+			failures[story.ID] = err
+		} else if doc, err := get(ctx, story.CommentsURL); err != nil {
+			failures[story.ID] = err
+		} else {
+			current := ExtractStory(doc.Find(".fatitem .athing").First())
+			story.Kind = current.Kind
+			story.Points = current.Points
+			story.Comments = current.Comments
+			story.PollOptions = ExtractPollOptions(doc.Selection)
+		}
+		if story.Kind == domain.PollStory {
+			polls = append(polls, story)
+		}
+	}
+	return
+}
+
+// mayBePoll reports whether story is known to be a poll or is a self-post,
+// which is how polls appear on listing pages.
+func mayBePoll(story domain.Story) bool {
+	if story.Kind == domain.PollStory {
+		return true
+	}
+	return story.Kind != domain.JobStory && strings.HasSuffix(story.URL, fmt.Sprintf("item?id=%v", story.ID))
+}
@@ -0,0 +1,106 @@
+package common
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"github.com/PuerkitoBio/goquery"
+
+	"github.com/jaytaylor/hn-utils/domain"
+)
+
+func TestPollTallies(t *testing.T) {
+	requested := map[string]int{}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/item", func(w http.ResponseWriter, req *http.Request) {
+		id := req.URL.Query().Get("id")
+		requested[id]++
+		switch id {
+		case "126809":
+			fmt.Fprint(w, pollItemHTML)
+		case "18914411":
+			fmt.Fprint(w, storyItemHTML)
+		default:
+			http.NotFound(w, req)
+		}
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	stories := domain.Stories{
+		// Not titled "Poll: ...", so only its item page reveals it's a poll.
+		{
+			ID:          126809,
+			Kind:        domain.AskStory,
+			Title:       "Ask HN: Which language do you use most?",
+			URL:         "https://news.ycombinator.com/item?id=126809",
+			Points:      1,
+			CommentsURL: server.URL + "/item?id=126809",
+		},
+		// Self-post which turns out not to be a poll.
+		{
+			ID:          18914411,
+			Kind:        domain.LinkStory,
+			URL:         "https://news.ycombinator.com/item?id=18914411",
+			CommentsURL: server.URL + "/item?id=18914411",
+		},
+		// Known poll whose item page fails keeps its previous tally.
+		{
+			ID:          126900,
+			Kind:        domain.PollStory,
+			Title:       "Poll: Tabs or spaces?",
+			URL:         "https://news.ycombinator.com/item?id=126900",
+			Points:      7,
+			CommentsURL: server.URL + "/item?id=126900",
+		},
+		// Link submissions can't be polls and aren't fetched.
+		{
+			ID:          3,
+			Kind:        domain.LinkStory,
+			URL:         "https://example.com/3",
+			CommentsURL: server.URL + "/item?id=3",
+		},
+	}
+
+	get := func(ctx context.Context, page string) (*goquery.Document, error) {
+		return GetDocument(ctx, server.Client(), page)
+	}
+	polls, failures := PollTallies(context.Background(), get, stories)
+
+	if expected, actual := 1, len(failures); actual != expected {
+		t.Fatalf("Expected len(failures)=%v but actual=%v", expected, actual)
+	}
+	if _, ok := failures[126900]; !ok {
+		t.Errorf("Expected failures to contain story 126900 but actual=%v", failures)
+	}
+	if expected, actual := 2, len(polls); actual != expected {
+		t.Fatalf("Expected len(polls)=%v but actual=%v", expected, actual)
+	}
+	if expected, actual := int64(126809), polls[0].ID; actual != expected {
+		t.Errorf("Expected polls[0].ID=%v but actual=%v", expected, actual)
+	}
+	if expected, actual := domain.PollStory, polls[0].Kind; actual != expected {
+		t.Errorf("Expected polls[0].Kind=%v but actual=%v", expected, actual)
+	}
+	if expected, actual := int64(400), polls[0].Points; actual != expected {
+		t.Errorf("Expected polls[0].Points=%v but actual=%v", expected, actual)
+	}
+	expectedOptions := []domain.PollOption{
+		{ID: 126810, Text: "Python", Points: 1061},
+		{ID: 126811, Text: "Go", Points: 487},
+	}
+	if !reflect.DeepEqual(polls[0].PollOptions, expectedOptions) {
+		t.Errorf("Expected polls[0].PollOptions=%+v but actual=%+v", expectedOptions, polls[0].PollOptions)
+	}
+	if expected, actual := int64(7), polls[1].Points; actual != expected {
+		t.Errorf("Expected polls[1].Points=%v but actual=%v", expected, actual)
+	}
+	if expected, actual := map[string]int{"126809": 1, "18914411": 1, "126900": 1}, requested; !reflect.DeepEqual(actual, expected) {
+		t.Errorf("Expected requests=%v but actual=%v", expected, actual)
+	}
+}
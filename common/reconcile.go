@@ -0,0 +1,97 @@
+package common
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/jaytaylor/hn-utils/domain"
+)
+
+// ErrEmptyListing is returned by ReconcileStories when the listing came back
+// empty while there are stored stories, which far more likely means the crawl
+// went wrong than that every story was removed.
+var ErrEmptyListing = errors.New("refusing to reconcile stored stories against an empty listing")
+
+// ReconcileSummary describes the changes a reconciliation made to the stored
+// stories.
+type ReconcileSummary struct {
+	Added    domain.Stories // Stories in the listing which weren't stored yet.
+	Removed  domain.Stories // Stored stories no longer in the listing which were tombstoned, or dropped when pruning.
+	Restored domain.Stories // Tombstoned stories which are back in the listing.
+}
+
+func (s ReconcileSummary) String() string {
+	return fmt.Sprintf("%v added, %v removed, %v restored", len(s.Added), len(s.Removed), len(s.Restored))
+}
+
+// Print writes each added, removed and restored story to w, followed by the
+// totals.
+func (s ReconcileSummary) Print(w io.Writer) {
+	for _, change := range []struct {
+		stories domain.Stories
+		verb    string
+	}{
+		{s.Added, "Added"},
+		{s.Removed, "Removed"},
+		{s.Restored, "Restored"},
+	} {
+		for _, story := range change.stories {
+			fmt.Fprintf(w, "%v: %v %v\n", change.verb, story.ID, story.Title)
+		}
+	}
+	fmt.Fprintf(w, "Reconciled: %s\n", s)
+}
+
+// ReconcileStories merges live, the complete current contents of a listing,
+// into the existing stories like MergeStories does, then accounts for existing
+// stories which are gone from the listing: they are marked with a RemovedAt
+// time of now, or dropped altogether when prune is set.  Tombstoned stories
+// showing up in the listing again have their RemovedAt cleared.
+//
+// live must not be a partial crawl, otherwise every story beyond the point the
+// crawl stopped at is considered removed.  ErrEmptyListing is returned when
+// live is empty but existing isn't.
+func ReconcileStories(live domain.Stories, existing domain.Stories, now time.Time, prune bool) (domain.Stories, ReconcileSummary, error) {
+	if len(live) == 0 && len(existing) > 0 {
+		return nil, ReconcileSummary{}, ErrEmptyListing
+	}
+
+	var (
+		listed     = KnownIDs(live)
+		stored     = KnownIDs(existing)
+		tombstoned = map[int64]bool{}
+		reconciled = domain.Stories{}
+		summary    ReconcileSummary
+	)
+	for _, story := range existing {
+		if story.RemovedAt != nil {
+			tombstoned[story.ID] = true
+		}
+	}
+
+	for _, story := range MergeStories(live, existing) {
+		switch {
+		case listed[story.ID] && !stored[story.ID]:
+			summary.Added = append(summary.Added, story)
+
+		case listed[story.ID]:
+			// MergeStories already cleared RemovedAt.
+			if tombstoned[story.ID] {
+				summary.Restored = append(summary.Restored, story)
+			}
+
+		case prune:
+			summary.Removed = append(summary.Removed, story)
+			continue
+
+		case story.RemovedAt == nil:
+			removedAt := now
+			story.RemovedAt = &removedAt
+			summary.Removed = append(summary.Removed, story)
+		}
+		reconciled = append(reconciled, story)
+	}
+	return reconciled, summary, nil
+}
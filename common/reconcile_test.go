@@ -0,0 +1,111 @@
+package common
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/jaytaylor/hn-utils/domain"
+)
+
+func TestReconcileStories(t *testing.T) {
+	var (
+		earlier  = time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+		now      = time.Date(2026, 2, 3, 4, 5, 6, 0, time.UTC)
+		existing = domain.Stories{
+			{ID: 4},
+			{ID: 3},
+			{ID: 2, RemovedAt: &earlier},
+			{ID: 1, RemovedAt: &earlier},
+		}
+		live = domain.Stories{
+			{ID: 5},
+			{ID: 4},
+			{ID: 2},
+		}
+	)
+
+	testCases := []struct {
+		prune             bool
+		expectedIDs       []int64
+		expectedRemovedAt []*time.Time
+		expectedAdded     int
+		expectedRemoved   int
+		expectedRestored  int
+	}{
+		{
+			expectedIDs:       []int64{5, 4, 2, 3, 1},
+			expectedRemovedAt: []*time.Time{nil, nil, nil, &now, &earlier},
+			expectedAdded:     1,
+			expectedRemoved:   1,
+			expectedRestored:  1,
+		},
+		{
+			prune:             true,
+			expectedIDs:       []int64{5, 4, 2},
+			expectedRemovedAt: []*time.Time{nil, nil, nil},
+			expectedAdded:     1,
+			expectedRemoved:   2,
+			expectedRestored:  1,
+		},
+	}
+
+	for i, testCase := range testCases {
+		stories, summary, err := ReconcileStories(live, existing, now, testCase.prune)
+		if err != nil {
+			t.Errorf("[i=%v] %s", i, err)
+			continue
+		}
+		if expected, actual := len(testCase.expectedIDs), len(stories); actual != expected {
+			t.Errorf("[i=%v] Expected len(stories)=%v but actual=%v", i, expected, actual)
+			continue
+		}
+		for j, story := range stories {
+			if expected, actual := testCase.expectedIDs[j], story.ID; actual != expected {
+				t.Errorf("[i=%v] Expected stories[%v].ID=%v but actual=%v", i, j, expected, actual)
+			}
+			expected, actual := testCase.expectedRemovedAt[j], story.RemovedAt
+			if (expected == nil) != (actual == nil) || (expected != nil && !expected.Equal(*actual)) {
+				t.Errorf("[i=%v] Expected stories[%v].RemovedAt=%v but actual=%v", i, j, expected, actual)
+			}
+		}
+		if expected, actual := testCase.expectedAdded, len(summary.Added); actual != expected {
+			t.Errorf("[i=%v] Expected len(Added)=%v but actual=%v", i, expected, actual)
+		}
+		if expected, actual := testCase.expectedRemoved, len(summary.Removed); actual != expected {
+			t.Errorf("[i=%v] Expected len(Removed)=%v but actual=%v", i, expected, actual)
+		}
+		if expected, actual := testCase.expectedRestored, len(summary.Restored); actual != expected {
+			t.Errorf("[i=%v] Expected len(Restored)=%v but actual=%v", i, expected, actual)
+		}
+	}
+
+	// The existing stories must be left untouched.
+	if existing[1].RemovedAt != nil {
+		t.Errorf("Expected existing[1].RemovedAt=nil but actual=%v", existing[1].RemovedAt)
+	}
+}
+
+func TestReconcileStoriesEmptyListing(t *testing.T) {
+	existing := domain.Stories{{ID: 2}, {ID: 1}}
+	for _, prune := range []bool{false, true} {
+		if _, _, err := ReconcileStories(domain.Stories{}, existing, time.Now(), prune); err != ErrEmptyListing {
+			t.Errorf("[prune=%v] Expected err=%v but actual=%v", prune, ErrEmptyListing, err)
+		}
+	}
+	if _, _, err := ReconcileStories(domain.Stories{}, domain.Stories{}, time.Now(), false); err != nil {
+		t.Errorf("Expected no error reconciling an empty listing without stored stories but actual=%v", err)
+	}
+}
+
+func TestReconcileSummaryPrint(t *testing.T) {
+	summary := ReconcileSummary{
+		Added:   domain.Stories{{ID: 5, Title: "Five"}},
+		Removed: domain.Stories{{ID: 3, Title: "Three"}},
+	}
+	var buf bytes.Buffer
+	summary.Print(&buf)
+	if expected, actual := "Added: 5 Five\nRemoved: 3 Three\nReconciled: 1 added, 1 removed, 0 restored\n", buf.String(); actual != expected {
+		t.Errorf("Expected output=%q but actual=%q", expected, actual)
+	}
+}
@@ -0,0 +1,226 @@
+package common
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/cookiejar"
+	"net/url"
+	"sync"
+
+	"github.com/PuerkitoBio/goquery"
+	log "github.com/sirupsen/logrus"
+)
+
+// ErrNoCredentials is returned when opening a session without a password and
+// without a saved session to fall back on.
+var ErrNoCredentials = errors.New("no password supplied and no saved session found")
+
+// Session is a logged-in HN client.  Pages it retrieves are checked for signs
+// of having been logged out, in which case it transparently logs in again
+// once before giving up with ErrSessionExpired.
+type Session struct {
+	Client   *http.Client
+	BaseURL  string
+	Username string
+	Password string
+	Store    *SessionStore // Optional; saves cookies after logging in.
+
+	mu       sync.Mutex
+	relogged bool
+	restored bool  // Cookies came from Store and haven't yet been seen to work.
+	relogins int   // Re-login attempts so far, see relogin.
+	lastErr  error // Outcome of the latest re-login attempt.
+}
+
+// NewSession logs in to HN and returns the resulting session.
+func NewSession(ctx context.Context, username string, password string) (*Session, error) {
+	s := &Session{
+		Client:   NoAuthClient(),
+		BaseURL:  BaseURL,
+		Username: username,
+		Password: password,
+	}
+	if err := s.Login(ctx); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// OpenSession returns a session for the named user, reusing cookies saved in
+// store when available and logging in otherwise.  A nil store disables reuse.
+//
+// Restored sessions are not verified up front; should HN reject them, the
+// session logs in afresh on first use.
+func OpenSession(ctx context.Context, store *SessionStore, username string, password string) (*Session, error) {
+	s := &Session{
+		Client:   NoAuthClient(),
+		BaseURL:  BaseURL,
+		Username: username,
+		Password: password,
+		Store:    store,
+	}
+	if err := s.Open(ctx); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// Open restores the session from Store if possible and logs in otherwise.
+func (s *Session) Open(ctx context.Context) error {
+	restored, err := s.Restore()
+	if err != nil {
+		log.WithField("user", s.Username).Warnf("Ignoring saved session: %s", err)
+	}
+	if restored {
+		return nil
+	}
+	if s.Password == "" {
+		return ErrNoCredentials
+	}
+	return s.Login(ctx)
+}
+
+// Restore installs the cookies saved in Store, returning false if there are
+// none.
+func (s *Session) Restore() (bool, error) {
+	if s.Store == nil {
+		return false, nil
+	}
+	cookies, err := s.Store.Load(s.Username)
+	if err != nil || len(cookies) == 0 {
+		return false, err
+	}
+
+	u, err := url.Parse(s.BaseURL)
+	if err != nil {
+		return false, fmt.Errorf("parsing base URL: %s", err)
+	}
+	jar, err := cookiejar.New(nil)
+	if err != nil {
+		return false, fmt.Errorf("creating cookie jar: %s", err)
+	}
+	jar.SetCookies(u, cookies)
+	if !hasSessionCookie(jar, s.BaseURL) {
+		return false, nil
+	}
+
+	installJar(s.Client, jar)
+	s.restored = true
+	log.WithField("user", s.Username).Debugf("Restored saved session from %v", s.Store.Path)
+	return true, nil
+}
+
+// Login (re-)authenticates the session and saves the new cookies to Store.
+func (s *Session) Login(ctx context.Context) error {
+	if s.Password == "" {
+		return ErrNoCredentials
+	}
+	if err := Authenticate(ctx, s.Client, s.BaseURL, s.Username, s.Password); err != nil {
+		return err
+	}
+	log.WithField("user", s.Username).Debug("Logged in successfully")
+
+	if s.Store != nil {
+		u, _ := url.Parse(s.BaseURL)
+		if err := s.Store.Save(s.Username, s.Client.Jar.Cookies(u)); err != nil {
+			log.WithField("user", s.Username).Warnf("Unable to save session: %s", err)
+		}
+	}
+	return nil
+}
+
+// LoggedIn returns true when the session cookie is present.  It does not
+// verify the session with HN.
+func (s *Session) LoggedIn() bool {
+	return hasSessionCookie(s.Client.Jar, s.BaseURL)
+}
+
+// GetDocument retrieves and parses the specified page, re-logging in once if
+// HN served it to a logged-out visitor (see IsLoggedOut).  Pages without HN's
+// header, such as the plain "No such item." response, are returned as is.
+func (s *Session) GetDocument(ctx context.Context, page string) (*goquery.Document, error) {
+	s.mu.Lock()
+	seen := s.relogins
+	s.mu.Unlock()
+
+	doc, err := GetDocument(ctx, s.Client, page)
+	if err != nil {
+		return nil, err
+	}
+	if s.LoggedIn() && !IsLoggedOut(doc) {
+		if IsLoggedIn(doc) {
+			s.mu.Lock()
+			s.restored = false
+			s.mu.Unlock()
+		}
+		return doc, nil
+	}
+
+	if err := s.relogin(ctx, seen); err != nil {
+		return nil, err
+	}
+
+	if doc, err = GetDocument(ctx, s.Client, page); err != nil {
+		return nil, err
+	}
+	if IsLoggedOut(doc) {
+		return nil, fmt.Errorf("getting %v: %w", page, ErrSessionExpired)
+	}
+	return doc, nil
+}
+
+// Walk is like the package-level Walk, but fetches pages through the session.
+func (s *Session) Walk(ctx context.Context, page string, fn PageFunc) error {
+	return walk(ctx, s.GetDocument, page, fn)
+}
+
+// relogin logs in again, at most once over the lifetime of the session.  A
+// saved session which turns out to be invalid doesn't count towards the limit.
+//
+// seen is the number of re-login attempts made before the logged-out page was
+// requested.  Callers which raced with another attempt wait for it and share
+// its outcome instead of logging in yet again.
+func (s *Session) relogin(ctx context.Context, seen int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.relogins != seen {
+		return s.lastErr
+	}
+
+	switch {
+	case s.restored:
+		s.restored = false
+		log.WithField("user", s.Username).Info("Saved session is no longer valid, logging in")
+		if err := s.Store.Delete(s.Username); err != nil {
+			log.WithField("user", s.Username).Warnf("Unable to delete saved session: %s", err)
+		}
+	case s.relogged:
+		return ErrSessionExpired
+	default:
+		s.relogged = true
+		log.WithField("user", s.Username).Warn("Session expired, logging in again")
+	}
+
+	s.relogins++
+	s.lastErr = nil
+	if err := s.Login(ctx); err != nil {
+		s.lastErr = fmt.Errorf("%w: %s", ErrSessionExpired, err)
+	}
+	return s.lastErr
+}
+
+// IsLoggedIn returns true when the page header shows a logged-in user.
+func IsLoggedIn(doc *goquery.Document) bool {
+	return doc.Find("a#me").Length() > 0 || doc.Find("a#logout").Length() > 0
+}
+
+// IsLoggedOut returns true when the page header shows a login link instead of
+// a logged-in user.  Pages lacking HN's header altogether, e.g. "No such
+// user.", are neither logged in nor out.
+func IsLoggedOut(doc *goquery.Document) bool {
+	header := doc.Find(".pagetop")
+	return header.Length() > 0 && !IsLoggedIn(doc) && header.Find(`a[href^="login"]`).Length() > 0
+}
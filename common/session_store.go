@@ -0,0 +1,111 @@
+package common
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// SessionStore persists HN session cookies to a JSON file, keyed by username,
+// so subsequent runs can skip logging in.  The file is only readable by its
+// owner.
+type SessionStore struct {
+	Path string
+
+	mu sync.Mutex
+}
+
+// DefaultSessionStorePath returns the default location of the session file
+// within the user's configuration directory.
+func DefaultSessionStorePath() string {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		dir = os.TempDir()
+	}
+	return filepath.Join(dir, "hn-utils", "sessions.json")
+}
+
+// Load returns the cookies saved for the named user, or nil if there are none.
+func (store *SessionStore) Load(username string) ([]*http.Cookie, error) {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	sessions, err := store.read()
+	if err != nil {
+		return nil, err
+	}
+	return sessions[username], nil
+}
+
+// Save stores the cookies for the named user, replacing any previous ones.
+func (store *SessionStore) Save(username string, cookies []*http.Cookie) error {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	sessions, err := store.read()
+	if err != nil {
+		return err
+	}
+	sessions[username] = cookies
+	return store.write(sessions)
+}
+
+// Delete removes the cookies saved for the named user.
+func (store *SessionStore) Delete(username string) error {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	sessions, err := store.read()
+	if err != nil {
+		return err
+	}
+	if _, ok := sessions[username]; !ok {
+		return nil
+	}
+	delete(sessions, username)
+	return store.write(sessions)
+}
+
+func (store *SessionStore) read() (map[string][]*http.Cookie, error) {
+	sessions := map[string][]*http.Cookie{}
+
+	bs, err := ioutil.ReadFile(store.Path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return sessions, nil
+		}
+		return nil, fmt.Errorf("reading session store: %s", err)
+	}
+	if err := json.Unmarshal(bs, &sessions); err != nil {
+		return nil, fmt.Errorf("decoding session store %v: %s", store.Path, err)
+	}
+	return sessions, nil
+}
+
+func (store *SessionStore) write(sessions map[string][]*http.Cookie) error {
+	bs, err := json.MarshalIndent(sessions, "", "    ")
+	if err != nil {
+		return fmt.Errorf("encoding session store: %s", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(store.Path), 0700); err != nil {
+		return fmt.Errorf("creating session store directory: %s", err)
+	}
+
+	// Write to a temporary file first so a crash can't leave a truncated store
+	// behind.
+	tmp := store.Path + ".tmp"
+	if err := ioutil.WriteFile(tmp, bs, 0600); err != nil {
+		return fmt.Errorf("writing session store: %s", err)
+	}
+	if err := os.Chmod(tmp, 0600); err != nil {
+		return fmt.Errorf("securing session store: %s", err)
+	}
+	if err := os.Rename(tmp, store.Path); err != nil {
+		return fmt.Errorf("writing session store: %s", err)
+	}
+	return nil
+}
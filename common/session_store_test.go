@@ -0,0 +1,94 @@
+package common
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestSessionStore(t *testing.T) {
+	store := &SessionStore{Path: filepath.Join(t.TempDir(), "nested", "sessions.json")}
+
+	if cookies, err := store.Load("alice"); err != nil || cookies != nil {
+		t.Fatalf("Expected no cookies and no error from empty store but actual cookies=%v err=%v", cookies, err)
+	}
+
+	if err := store.Save("alice", []*http.Cookie{{Name: SessionCookie, Value: "alice&1"}}); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Save("bob", []*http.Cookie{{Name: SessionCookie, Value: "bob&1"}}); err != nil {
+		t.Fatal(err)
+	}
+
+	info, err := os.Stat(store.Path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected, actual := os.FileMode(0600), info.Mode().Perm(); actual != expected {
+		t.Errorf("Expected session store permissions=%v but actual=%v", expected, actual)
+	}
+
+	cookies, err := store.Load("alice")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(cookies) != 1 || cookies[0].Value != "alice&1" {
+		t.Errorf("Expected alice's saved cookie but actual=%v", cookies)
+	}
+
+	if err := store.Delete("alice"); err != nil {
+		t.Fatal(err)
+	}
+	if cookies, _ := store.Load("alice"); cookies != nil {
+		t.Errorf("Expected alice's cookies to be deleted but actual=%v", cookies)
+	}
+	if cookies, _ := store.Load("bob"); len(cookies) != 1 {
+		t.Errorf("Expected bob's cookies to remain but actual=%v", cookies)
+	}
+}
+
+func TestSessionRestore(t *testing.T) {
+	var (
+		hn    = newFakeHN(t)
+		store = &SessionStore{Path: filepath.Join(t.TempDir(), "sessions.json")}
+		ctx   = context.Background()
+	)
+
+	session := hn.session()
+	session.Store = store
+	if err := session.Open(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	// A second run reuses the saved session without a password.
+	restored := hn.session()
+	restored.Password = ""
+	restored.Store = store
+	if err := restored.Open(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := restored.GetDocument(ctx, hn.URL+"/news"); err != nil {
+		t.Fatal(err)
+	}
+	if expected, actual := 1, hn.loginCount(); actual != expected {
+		t.Errorf("Expected logins=%v but actual=%v", expected, actual)
+	}
+
+	// Once the saved session is invalid, a password is needed again.
+	hn.expire()
+	stale := hn.session()
+	stale.Password = ""
+	stale.Store = store
+	if err := stale.Open(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := stale.GetDocument(ctx, hn.URL+"/news"); !errors.Is(err, ErrSessionExpired) {
+		t.Fatalf("Expected ErrSessionExpired but actual=%v", err)
+	}
+	if cookies, _ := store.Load("alice"); cookies != nil {
+		t.Errorf("Expected invalid saved session to be deleted but actual=%v", cookies)
+	}
+}
@@ -0,0 +1,194 @@
+package common
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"sync"
+	"testing"
+)
+
+// fakeHN is a minimal stand-in for HN's login and page handling.  Each
+// successful login issues a new session token; expire() invalidates the
+// current one.
+type fakeHN struct {
+	*httptest.Server
+
+	mu     sync.Mutex
+	token  int
+	logins int
+}
+
+func newFakeHN(t *testing.T) *fakeHN {
+	hn := &fakeHN{}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/login", func(w http.ResponseWriter, req *http.Request) {
+		if req.FormValue("acct") != "alice" || req.FormValue("pw") != "secret" {
+			fmt.Fprint(w, "<html><body>Bad login.</body></html>")
+			return
+		}
+		hn.mu.Lock()
+		hn.logins++
+		hn.token++
+		value := fmt.Sprintf("alice&%v", hn.token)
+		hn.mu.Unlock()
+		http.SetCookie(w, &http.Cookie{Name: SessionCookie, Value: value, Path: "/"})
+		http.Redirect(w, req, "news", http.StatusFound)
+	})
+	mux.HandleFunc("/news", func(w http.ResponseWriter, req *http.Request) {
+		hn.mu.Lock()
+		current := fmt.Sprintf("alice&%v", hn.token)
+		hn.mu.Unlock()
+		cookie, err := req.Cookie(SessionCookie)
+		if err != nil || cookie.Value != current {
+			fmt.Fprint(w, `<html><body><span class="pagetop"><a href="login?goto=news">login</a></span></body></html>`)
+			return
+		}
+		fmt.Fprint(w, `<html><body><span class="pagetop"><a id="me" href="user?id=alice">alice</a></span></body></html>`)
+	})
+	mux.HandleFunc("/item", func(w http.ResponseWriter, req *http.Request) {
+		fmt.Fprint(w, "No such item.")
+	})
+	hn.Server = httptest.NewServer(mux)
+	t.Cleanup(hn.Close)
+	return hn
+}
+
+// expire invalidates the current session token.
+func (hn *fakeHN) expire() {
+	hn.mu.Lock()
+	hn.token++
+	hn.mu.Unlock()
+}
+
+// loginCount returns the number of successful logins so far.
+func (hn *fakeHN) loginCount() int {
+	hn.mu.Lock()
+	defer hn.mu.Unlock()
+	return hn.logins
+}
+
+func (hn *fakeHN) session() *Session {
+	return &Session{
+		Client:   NoAuthClient(),
+		BaseURL:  hn.URL,
+		Username: "alice",
+		Password: "secret",
+	}
+}
+
+func TestSessionBadLogin(t *testing.T) {
+	hn := newFakeHN(t)
+
+	session := hn.session()
+	session.Password = "wrong"
+	if err := session.Login(context.Background()); !errors.Is(err, ErrBadLogin) {
+		t.Fatalf("Expected ErrBadLogin but actual=%v", err)
+	}
+}
+
+func TestSessionRelogin(t *testing.T) {
+	var (
+		hn      = newFakeHN(t)
+		session = hn.session()
+		ctx     = context.Background()
+	)
+
+	if err := session.Login(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := session.GetDocument(ctx, hn.URL+"/news"); err != nil {
+		t.Fatal(err)
+	}
+
+	// The first expiry is handled transparently.
+	hn.expire()
+	if _, err := session.GetDocument(ctx, hn.URL+"/news"); err != nil {
+		t.Fatal(err)
+	}
+	if expected, actual := 2, hn.loginCount(); actual != expected {
+		t.Errorf("Expected logins=%v but actual=%v", expected, actual)
+	}
+
+	// Subsequent ones are not.
+	hn.expire()
+	if _, err := session.GetDocument(ctx, hn.URL+"/news"); !errors.Is(err, ErrSessionExpired) {
+		t.Fatalf("Expected ErrSessionExpired but actual=%v", err)
+	}
+}
+
+func TestSessionConcurrentRelogin(t *testing.T) {
+	const workers = 8
+
+	for _, restore := range []bool{false, true} {
+		var (
+			hn      = newFakeHN(t)
+			session = hn.session()
+			ctx     = context.Background()
+		)
+
+		if restore {
+			// Expiry of a saved session is handled the same way.
+			store := &SessionStore{Path: filepath.Join(t.TempDir(), "sessions.json")}
+			saver := hn.session()
+			saver.Store = store
+			if err := saver.Login(ctx); err != nil {
+				t.Fatal(err)
+			}
+			session.Store = store
+		}
+		if err := session.Open(ctx); err != nil {
+			t.Fatal(err)
+		}
+		hn.expire()
+
+		var (
+			wg   sync.WaitGroup
+			errs = make(chan error, workers)
+		)
+		for i := 0; i < workers; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				_, err := session.GetDocument(ctx, hn.URL+"/news")
+				errs <- err
+			}()
+		}
+		wg.Wait()
+		close(errs)
+
+		for err := range errs {
+			if err != nil {
+				t.Errorf("[restore=%v] Unexpected error: %s", restore, err)
+			}
+		}
+		if expected, actual := 2, hn.loginCount(); actual != expected {
+			t.Errorf("[restore=%v] Expected logins=%v but actual=%v", restore, expected, actual)
+		}
+	}
+}
+
+func TestSessionPageWithoutHeader(t *testing.T) {
+	var (
+		hn      = newFakeHN(t)
+		session = hn.session()
+		ctx     = context.Background()
+	)
+
+	if err := session.Login(ctx); err != nil {
+		t.Fatal(err)
+	}
+	doc, err := session.GetDocument(ctx, hn.URL+"/item?id=0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected, actual := "No such item.", doc.Text(); actual != expected {
+		t.Errorf("Expected text=%q but actual=%q", expected, actual)
+	}
+	if expected, actual := 1, hn.loginCount(); actual != expected {
+		t.Errorf("Expected logins=%v but actual=%v", expected, actual)
+	}
+}
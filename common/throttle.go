@@ -0,0 +1,181 @@
+package common
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io/ioutil"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// RateLimitedMarker is the text of the interstitial page HN serves (with a 200
+// status) to clients which crawl too quickly.
+const RateLimitedMarker = "Sorry, we're not able to serve your requests this quickly."
+
+// Throttle is an http.RoundTripper which enforces a minimum delay between
+// requests and retries failed ones with exponential backoff and jitter.
+//
+// Network errors, 429 and 5xx responses, and the HN rate-limit page are all
+// considered retryable.  Once retries are exhausted, a rate-limit page is
+// surfaced as a 503 response.  Only GET and HEAD requests are retried, so that
+// e.g. a login POST is never submitted twice.
+type Throttle struct {
+	Transport   http.RoundTripper // Underlying transport, defaults to http.DefaultTransport.
+	MinInterval time.Duration     // Minimum delay between the start of consecutive requests.
+	MaxRetries  int               // Maximum number of retries per request.
+	Backoff     time.Duration     // Initial backoff delay, doubled after each failed attempt.
+	MaxBackoff  time.Duration     // Upper bound for a single backoff delay.
+
+	mu   sync.Mutex
+	next time.Time
+}
+
+// RoundTrip implements http.RoundTripper.
+func (t *Throttle) RoundTrip(req *http.Request) (*http.Response, error) {
+	maxRetries := t.MaxRetries
+	if req.Method != http.MethodGet && req.Method != http.MethodHead {
+		maxRetries = 0
+	}
+
+	for attempt := 0; ; attempt++ {
+		if err := t.wait(req.Context()); err != nil {
+			return nil, err
+		}
+
+		// The caller's request must not be modified, so retries send a
+		// clone with a fresh body.
+		r := req
+		if attempt > 0 {
+			r = req.Clone(req.Context())
+			if req.Body != nil && req.Body != http.NoBody {
+				if req.GetBody == nil {
+					return nil, fmt.Errorf("retrying %v %v: request body cannot be replayed", req.Method, req.URL)
+				}
+				body, err := req.GetBody()
+				if err != nil {
+					return nil, err
+				}
+				r.Body = body
+			}
+		}
+
+		resp, err := t.transport().RoundTrip(r)
+		retryable, reason, err := t.check(resp, err)
+		if !retryable || attempt >= maxRetries || req.Context().Err() != nil {
+			if err != nil {
+				return nil, err
+			}
+			if reason == RateLimitedMarker {
+				resp.StatusCode = http.StatusServiceUnavailable
+				resp.Status = fmt.Sprintf("%v %v", resp.StatusCode, http.StatusText(resp.StatusCode))
+			}
+			return resp, nil
+		}
+
+		delay := t.backoff(attempt, resp)
+		if resp != nil {
+			resp.Body.Close()
+		}
+		log.WithField("url", req.URL.String()).WithField("attempt", attempt+1).Warnf("Request failed (%v), retrying in %v", reason, delay)
+
+		if err := sleep(req.Context(), delay); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// check determines whether a response or error warrants a retry.
+func (t *Throttle) check(resp *http.Response, err error) (bool, string, error) {
+	if err != nil {
+		return true, err.Error(), err
+	}
+	if resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode/100 == 5 {
+		return true, resp.Status, nil
+	}
+	if resp.StatusCode/100 != 2 || !strings.HasPrefix(resp.Header.Get("Content-Type"), "text/html") {
+		return false, "", nil
+	}
+
+	body, err := ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return true, err.Error(), fmt.Errorf("reading response body: %s", err)
+	}
+	resp.Body = ioutil.NopCloser(bytes.NewReader(body))
+
+	if bytes.Contains(body, []byte(RateLimitedMarker)) {
+		return true, RateLimitedMarker, nil
+	}
+	return false, "", nil
+}
+
+// wait blocks until the minimum interval since the previous request has
+// elapsed.
+func (t *Throttle) wait(ctx context.Context) error {
+	t.mu.Lock()
+	var (
+		now   = time.Now()
+		start = t.next
+	)
+	if start.Before(now) {
+		start = now
+	}
+	t.next = start.Add(t.MinInterval)
+	t.mu.Unlock()
+
+	return sleep(ctx, start.Sub(now))
+}
+
+// backoff returns the delay before the next attempt, honoring any Retry-After
+// header sent by the server up to MaxBackoff.
+func (t *Throttle) backoff(attempt int, resp *http.Response) time.Duration {
+	if resp != nil {
+		if secs, err := strconv.Atoi(resp.Header.Get("Retry-After")); err == nil && secs > 0 {
+			d := time.Duration(secs) * time.Second
+			if t.MaxBackoff > 0 && d > t.MaxBackoff {
+				d = t.MaxBackoff
+			}
+			return d
+		}
+	}
+
+	d := t.Backoff << uint(attempt)
+	if d <= 0 || (t.MaxBackoff > 0 && d > t.MaxBackoff) {
+		d = t.MaxBackoff
+	}
+	if d <= 0 {
+		return 0
+	}
+	// Jitter into [d/2, d) so concurrent clients spread out.
+	return d/2 + time.Duration(rand.Int63n(int64(d/2)+1))
+}
+
+func (t *Throttle) transport() http.RoundTripper {
+	if t.Transport == nil {
+		return http.DefaultTransport
+	}
+	return t.Transport
+}
+
+// sleep waits for the specified duration or until ctx is done.
+func sleep(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return ctx.Err()
+	}
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
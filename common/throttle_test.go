@@ -0,0 +1,214 @@
+package common
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestThrottleRetries(t *testing.T) {
+	var attempts int
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		attempts++
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		switch attempts {
+		case 1:
+			w.WriteHeader(http.StatusServiceUnavailable)
+		case 2:
+			fmt.Fprintf(w, "<html><body>%v</body></html>", RateLimitedMarker)
+		default:
+			fmt.Fprint(w, "<html><body>ok</body></html>")
+		}
+	}))
+	defer server.Close()
+
+	client := &http.Client{
+		Transport: &Throttle{
+			MaxRetries: 3,
+			Backoff:    time.Millisecond,
+		},
+	}
+
+	rc, err := CheckedGet(context.Background(), client, server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rc.Close()
+
+	body, err := ioutil.ReadAll(rc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected, actual := "<html><body>ok</body></html>", string(body); actual != expected {
+		t.Errorf("Expected body=%q but actual=%q", expected, actual)
+	}
+	if expected, actual := 3, attempts; actual != expected {
+		t.Errorf("Expected attempts=%v but actual=%v", expected, actual)
+	}
+}
+
+func TestThrottleRateLimitedExhausted(t *testing.T) {
+	var attempts int
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		attempts++
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		fmt.Fprintf(w, "<html><body>%v</body></html>", RateLimitedMarker)
+	}))
+	defer server.Close()
+
+	client := &http.Client{
+		Transport: &Throttle{
+			MaxRetries: 2,
+			Backoff:    time.Millisecond,
+		},
+	}
+
+	if _, err := CheckedGet(context.Background(), client, server.URL); err == nil {
+		t.Fatal("Expected error for persistent rate-limit page but got nil")
+	}
+	if expected, actual := 3, attempts; actual != expected {
+		t.Errorf("Expected attempts=%v but actual=%v", expected, actual)
+	}
+}
+
+func TestThrottleMinInterval(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(_ http.ResponseWriter, _ *http.Request) {}))
+	defer server.Close()
+
+	var (
+		interval = 20 * time.Millisecond
+		client   = &http.Client{Transport: &Throttle{MinInterval: interval}}
+		start    = time.Now()
+	)
+
+	for i := 0; i < 3; i++ {
+		rc, err := CheckedGet(context.Background(), client, server.URL)
+		if err != nil {
+			t.Fatal(err)
+		}
+		rc.Close()
+	}
+
+	if elapsed := time.Since(start); elapsed < 2*interval {
+		t.Errorf("Expected 3 requests to take at least %v but took %v", 2*interval, elapsed)
+	}
+}
+
+func TestThrottleBackoff(t *testing.T) {
+	testCases := []struct {
+		throttle   *Throttle
+		retryAfter string
+		expected   time.Duration
+	}{
+		{
+			throttle:   &Throttle{Backoff: time.Second},
+			retryAfter: "120",
+			expected:   2 * time.Minute,
+		},
+		{
+			throttle:   &Throttle{Backoff: time.Second, MaxBackoff: time.Minute},
+			retryAfter: "86400",
+			expected:   time.Minute,
+		},
+		{
+			throttle:   &Throttle{Backoff: time.Second, MaxBackoff: time.Minute},
+			retryAfter: "30",
+			expected:   30 * time.Second,
+		},
+	}
+
+	for i, testCase := range testCases {
+		resp := &http.Response{Header: http.Header{"Retry-After": []string{testCase.retryAfter}}}
+		if actual := testCase.throttle.backoff(0, resp); actual != testCase.expected {
+			t.Errorf("[i=%v] Expected backoff=%v but actual=%v", i, testCase.expected, actual)
+		}
+	}
+}
+
+// roundTripFunc adapts a func to http.RoundTripper.
+type roundTripFunc func(req *http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+// failingBody is a response body whose reads fail.
+type failingBody struct{}
+
+func (failingBody) Read([]byte) (int, error) { return 0, errors.New("connection reset") }
+func (failingBody) Close() error             { return nil }
+
+func TestThrottleRetriesOnlyIdempotent(t *testing.T) {
+	var attempts int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		attempts++
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	client := &http.Client{Transport: &Throttle{MaxRetries: 3, Backoff: time.Millisecond}}
+
+	req, err := http.NewRequest(http.MethodPost, server.URL, strings.NewReader("acct=pg"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if expected, actual := 1, attempts; actual != expected {
+		t.Errorf("Expected POST attempts=%v but actual=%v", expected, actual)
+	}
+
+	// GETs are retried with clones, leaving the caller's request as it was.
+	attempts = 0
+	req, err = http.NewRequest(http.MethodGet, server.URL, strings.NewReader("q=1"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	body := req.Body
+	if resp, err = client.Transport.RoundTrip(req); err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if expected, actual := 4, attempts; actual != expected {
+		t.Errorf("Expected GET attempts=%v but actual=%v", expected, actual)
+	}
+	if req.Body != body {
+		t.Errorf("Expected the caller's request body to be left untouched")
+	}
+}
+
+func TestThrottleBodyReadFailure(t *testing.T) {
+	throttle := &Throttle{
+		Transport: roundTripFunc(func(req *http.Request) (*http.Response, error) {
+			return &http.Response{
+				StatusCode: http.StatusOK,
+				Header:     http.Header{"Content-Type": []string{"text/html; charset=utf-8"}},
+				Body:       failingBody{},
+				Request:    req,
+			}, nil
+		}),
+	}
+
+	req, err := http.NewRequest(http.MethodGet, "https://news.ycombinator.com/", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err := throttle.RoundTrip(req)
+	if err == nil {
+		t.Fatal("Expected body read error but actual=nil")
+	}
+	if resp != nil {
+		t.Errorf("Expected nil response alongside the error but actual=%+v", resp)
+	}
+}
@@ -0,0 +1,24 @@
+package domain
+
+// SchemaVersion is the version of the serialized data format.  It is bumped
+// whenever a change breaks existing consumers, i.e. fields being renamed,
+// removed or changing type.  Adding fields does not bump it.
+//
+// Version 0 denotes the legacy format: a bare array of stories keyed by Go
+// field names ("CommentsURL", "N", ...).
+const SchemaVersion = 1
+
+// Database is the top-level document written by the tools.
+type Database struct {
+	SchemaVersion int           `json:"schema_version"     yaml:"schema_version"`
+	Stories       Stories       `json:"stories"            yaml:"stories"`
+	Comments      []UserComment `json:"comments,omitempty" yaml:"comments,omitempty"` // Comment history, for the "comments" section.
+}
+
+// NewDatabase wraps stories in a Database of the current schema version.
+func NewDatabase(stories Stories) Database {
+	return Database{
+		SchemaVersion: SchemaVersion,
+		Stories:       stories,
+	}
+}
@@ -11,15 +11,32 @@ const CommentNestingWidthIncrement = 40
 
 // Comment is a representation of a HackerNews comment.
 type Comment struct {
-	ID        int64
-	Author    string
-	Timestamp time.Time
-	Content   string
-	Width     int // Width indicates nesting depth, divide by 40 to get depth.
-	N         int // N is the number of children in thread, as reported by the original data source.
-	Children  Threads
+	ID        int64     `json:"id"                  yaml:"id"`
+	Author    string    `json:"author"              yaml:"author"`
+	Timestamp time.Time `json:"timestamp"           yaml:"timestamp"`
+	Content   string    `json:"content"             yaml:"content"`
+	HTML      string    `json:"html,omitempty"      yaml:"html,omitempty"`     // Sanitized original HTML of the comment text.
+	Markdown  string    `json:"markdown,omitempty"  yaml:"markdown,omitempty"` // Markdown rendering of the comment text.
+	Links     []Link    `json:"links,omitempty"     yaml:"links,omitempty"`    // Outbound links in the comment text.
+	Width     int       `json:"width"               yaml:"width"`              // Width indicates nesting depth, divide by 40 to get depth.
+	N         int       `json:"thread_size"         yaml:"thread_size"`        // N is the number of comments in this thread (including this one), as reported by the original data source.
+	Dead      bool      `json:"dead,omitempty"      yaml:"dead,omitempty"`
+	Flagged   bool      `json:"flagged,omitempty"   yaml:"flagged,omitempty"`
+	Deleted   bool      `json:"deleted,omitempty"   yaml:"deleted,omitempty"`
+	Collapsed bool      `json:"collapsed,omitempty" yaml:"collapsed,omitempty"` // Collapsed indicates the comment (or an ancestor) was folded away on the page.
+	Children  Threads   `json:"children"            yaml:"children"`
 }
 
+// Link is a hyperlink found in a comment.
+type Link struct {
+	URL  string `json:"url"  yaml:"url"`
+	Text string `json:"text" yaml:"text"` // Anchor text, which HN truncates for long URLs.
+}
+
+// Comments is a flat group of comment values.
+//
+// Deprecated: Story.Children now holds the Threads tree.  Both serialize to
+// the same JSON, so data written with Comments still decodes into Threads.
 type Comments []Comment
 
 // Threads is a group of comments.
@@ -30,6 +47,12 @@ func (c Comment) Depth() int {
 	return c.Width / CommentNestingWidthIncrement
 }
 
+// Removed returns true when the comment's text is no longer shown because it
+// was killed, flagged or deleted.
+func (c Comment) Removed() bool {
+	return c.Dead || c.Flagged || c.Deleted
+}
+
 // ConversationLen recursively returns the number of descendent comments under
 // this one.
 //
@@ -0,0 +1,33 @@
+// Command genschema writes the JSON Schema documents describing the
+// serialized data format.  Run it via `go generate ./domain`.
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/jaytaylor/hn-utils/domain"
+
+	log "github.com/sirupsen/logrus"
+)
+
+func main() {
+	out := flag.String("out", "schema", "Output directory")
+	flag.Parse()
+
+	if err := os.MkdirAll(*out, 0755); err != nil {
+		log.Fatal(err)
+	}
+	for name, doc := range domain.SchemaDocuments() {
+		bs, err := json.MarshalIndent(doc, "", "    ")
+		if err != nil {
+			log.Fatal(err)
+		}
+		if err := ioutil.WriteFile(filepath.Join(*out, name), append(bs, '\n'), 0644); err != nil {
+			log.Fatal(err)
+		}
+	}
+}
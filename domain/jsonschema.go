@@ -0,0 +1,156 @@
+package domain
+
+//go:generate go run ./internal/genschema -out ../schema
+
+import (
+	"reflect"
+	"strings"
+	"time"
+	"unicode"
+)
+
+var timeType = reflect.TypeOf(time.Time{})
+
+// SchemaDocuments returns the JSON Schema (draft-07) documents describing the
+// serialized data format, keyed by file name.
+func SchemaDocuments() map[string]map[string]interface{} {
+	return map[string]map[string]interface{}{
+		"database.schema.json":     JSONSchema(Database{}),
+		"story.schema.json":        JSONSchema(Story{}),
+		"comment.schema.json":      JSONSchema(Comment{}),
+		"user.schema.json":         JSONSchema(User{}),
+		"user_comment.schema.json": JSONSchema(UserComment{}),
+	}
+}
+
+// JSONSchema returns a JSON Schema (draft-07) document describing the JSON
+// serialization of v, which must be a struct.  Struct types are emitted as
+// definitions so recursive types such as Comment can be expressed.
+func JSONSchema(v interface{}) map[string]interface{} {
+	var (
+		t = reflect.TypeOf(v)
+		b = schemaBuilder{definitions: map[string]interface{}{}}
+	)
+
+	b.schema(t)
+
+	doc := map[string]interface{}{
+		"$schema":     "http://json-schema.org/draft-07/schema#",
+		"title":       t.Name(),
+		"definitions": b.definitions,
+	}
+	for k, v := range b.definitions[definitionName(t)].(map[string]interface{}) {
+		doc[k] = v
+	}
+	doc["$id"] = "https://github.com/jaytaylor/hn-utils/schema/" + definitionName(t) + ".schema.json"
+	return doc
+}
+
+type schemaBuilder struct {
+	definitions map[string]interface{}
+}
+
+func (b *schemaBuilder) schema(t reflect.Type) map[string]interface{} {
+	if t == timeType {
+		return map[string]interface{}{"type": "string", "format": "date-time"}
+	}
+
+	switch t.Kind() {
+	case reflect.Ptr:
+		return b.schema(t.Elem())
+
+	case reflect.Bool:
+		return map[string]interface{}{"type": "boolean"}
+
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return map[string]interface{}{"type": "integer"}
+
+	case reflect.Float32, reflect.Float64:
+		return map[string]interface{}{"type": "number"}
+
+	case reflect.String:
+		return map[string]interface{}{"type": "string"}
+
+	case reflect.Slice, reflect.Array:
+		// Nil slices serialize as null.
+		return map[string]interface{}{
+			"type":  []string{"array", "null"},
+			"items": b.schema(t.Elem()),
+		}
+
+	case reflect.Map:
+		return map[string]interface{}{
+			"type":                 "object",
+			"additionalProperties": b.schema(t.Elem()),
+		}
+
+	case reflect.Struct:
+		name := definitionName(t)
+		if _, ok := b.definitions[name]; !ok {
+			b.definitions[name] = nil // Placeholder to terminate recursion.
+			b.definitions[name] = b.object(t)
+		}
+		return map[string]interface{}{"$ref": "#/definitions/" + name}
+	}
+
+	return map[string]interface{}{}
+}
+
+func (b *schemaBuilder) object(t reflect.Type) map[string]interface{} {
+	var (
+		properties = map[string]interface{}{}
+		required   = []string{}
+	)
+
+	b.fields(t, properties, &required)
+
+	return map[string]interface{}{
+		"type":                 "object",
+		"properties":           properties,
+		"required":             required,
+		"additionalProperties": false,
+	}
+}
+
+// fields adds the properties of the fields of t, flattening untagged embedded
+// structs the way encoding/json does.
+func (b *schemaBuilder) fields(t reflect.Type, properties map[string]interface{}, required *[]string) {
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		name, opts := parseTag(field.Tag.Get("json"))
+		if field.Anonymous && name == "" && field.Type.Kind() == reflect.Struct {
+			b.fields(field.Type, properties, required)
+			continue
+		}
+		if field.PkgPath != "" || name == "-" {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+		properties[name] = b.schema(field.Type)
+		if !strings.Contains(opts, "omitempty") {
+			*required = append(*required, name)
+		}
+	}
+}
+
+func parseTag(tag string) (string, string) {
+	if i := strings.Index(tag, ","); i >= 0 {
+		return tag[:i], tag[i+1:]
+	}
+	return tag, ""
+}
+
+// definitionName converts a type name to snake_case, e.g. "user_comment".
+func definitionName(t reflect.Type) string {
+	var name strings.Builder
+	for i, r := range t.Name() {
+		if i > 0 && unicode.IsUpper(r) {
+			name.WriteByte('_')
+		}
+		name.WriteRune(unicode.ToLower(r))
+	}
+	return name.String()
+}
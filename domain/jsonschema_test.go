@@ -0,0 +1,27 @@
+package domain
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+)
+
+// TestSchemaDocumentsUpToDate guards against forgetting to regenerate the
+// committed schema documents after changing the data model.
+func TestSchemaDocumentsUpToDate(t *testing.T) {
+	for name, doc := range SchemaDocuments() {
+		expected, err := json.MarshalIndent(doc, "", "    ")
+		if err != nil {
+			t.Fatal(err)
+		}
+		actual, err := ioutil.ReadFile(filepath.Join("..", "schema", name))
+		if err != nil {
+			t.Errorf("%s (run `go generate ./domain`)", err)
+			continue
+		}
+		if string(actual) != string(expected)+"\n" {
+			t.Errorf("Schema document %v is out of date (run `go generate ./domain`)", name)
+		}
+	}
+}
@@ -4,16 +4,49 @@ import (
 	"time"
 )
 
+// Story is a representation of a HackerNews story.
 type Story struct {
-	ID          int64
-	Title       string
-	URL         string
-	Points      int64
-	Comments    int64
-	CommentsURL string
-	Submitter   string
-	Timestamp   time.Time
-	Children    Comments
+	ID          int64        `json:"id"                     yaml:"id"`
+	Rank        int          `json:"rank,omitempty"         yaml:"rank,omitempty"`      // Position on the listing page the story was found on.
+	FrontDay    string       `json:"front_day,omitempty"    yaml:"front_day,omitempty"` // Day (YYYY-MM-DD) of the historical front page the story was found on.
+	Kind        StoryKind    `json:"kind"                   yaml:"kind"`
+	Title       string       `json:"title"                  yaml:"title"`
+	URL         string       `json:"url"                    yaml:"url"`
+	Site        string       `json:"site,omitempty"         yaml:"site,omitempty"` // Domain shown next to the title.
+	Points      int64        `json:"points"                 yaml:"points"`
+	Comments    int64        `json:"comments"               yaml:"comments"`
+	CommentsURL string       `json:"comments_url"           yaml:"comments_url"`
+	Submitter   string       `json:"submitter"              yaml:"submitter"`
+	Timestamp   time.Time    `json:"timestamp"              yaml:"timestamp"`
+	Dead        bool         `json:"dead,omitempty"         yaml:"dead,omitempty"`
+	Flagged     bool         `json:"flagged,omitempty"      yaml:"flagged,omitempty"`
+	Dupe        bool         `json:"dupe,omitempty"         yaml:"dupe,omitempty"`
+	Hidden      bool         `json:"hidden,omitempty"       yaml:"hidden,omitempty"`       // Hidden by the logged-in user.
+	RemovedAt   *time.Time   `json:"removed_at,omitempty"   yaml:"removed_at,omitempty"`   // When a reconciling run found the story gone from its listing (e.g. unfavorited).
+	Text        string       `json:"text,omitempty"         yaml:"text,omitempty"`         // Body of self-posts such as "Ask HN", only populated when the item page was fetched.
+	TextHTML    string       `json:"text_html,omitempty"    yaml:"text_html,omitempty"`    // Original HTML of Text.
+	PollOptions []PollOption `json:"poll_options,omitempty" yaml:"poll_options,omitempty"` // Options and their current scores, only populated for polls when the item page was fetched.
+	Children    Threads      `json:"children"               yaml:"children"`               // Discussion tree, only populated when the item page was fetched.
 }
 
+// PollOption is one of the options of a poll.
+type PollOption struct {
+	ID     int64  `json:"id"     yaml:"id"`
+	Text   string `json:"text"   yaml:"text"`
+	Points int64  `json:"points" yaml:"points"`
+}
+
+// StoryKind classifies stories.
+type StoryKind string
+
+const (
+	LinkStory   StoryKind = "link"
+	AskStory    StoryKind = "ask"
+	ShowStory   StoryKind = "show"
+	LaunchStory StoryKind = "launch"
+	TellStory   StoryKind = "tell"
+	PollStory   StoryKind = "poll"
+	JobStory    StoryKind = "job"
+)
+
 type Stories []Story
@@ -0,0 +1,24 @@
+package domain
+
+import (
+	"time"
+)
+
+// User is a representation of a HackerNews user profile.
+type User struct {
+	ID             string    `json:"id"                   yaml:"id"`
+	Created        time.Time `json:"created"              yaml:"created"`
+	Karma          int64     `json:"karma"                yaml:"karma"`
+	About          string    `json:"about,omitempty"      yaml:"about,omitempty"`
+	AboutHTML      string    `json:"about_html,omitempty" yaml:"about_html,omitempty"` // Original HTML of About.
+	SubmissionsURL string    `json:"submissions_url"      yaml:"submissions_url"`
+	CommentsURL    string    `json:"comments_url"         yaml:"comments_url"`
+	FavoritesURL   string    `json:"favorites_url"        yaml:"favorites_url"`
+}
+
+// KarmaSnapshot records the karma of a user at a point in time.
+type KarmaSnapshot struct {
+	User      string    `json:"user"      yaml:"user"`
+	Karma     int64     `json:"karma"     yaml:"karma"`
+	Timestamp time.Time `json:"timestamp" yaml:"timestamp"`
+}
@@ -0,0 +1,11 @@
+package domain
+
+// UserComment is a comment from a user's comment history along with the
+// context it was made in.  Children holds its visible replies.
+type UserComment struct {
+	Comment `yaml:",inline"`
+
+	ParentID   int64  `json:"parent_id"   yaml:"parent_id"` // ID of the story or comment replied to.
+	StoryID    int64  `json:"story_id"    yaml:"story_id"`
+	StoryTitle string `json:"story_title" yaml:"story_title"`
+}
@@ -0,0 +1,228 @@
+// Package hn is a client library for retrieving structured data from
+// HackerNews (news.ycombinator.com) via scraping.
+package hn
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sync"
+
+	"github.com/jaytaylor/hn-utils/common"
+	"github.com/jaytaylor/hn-utils/domain"
+
+	"github.com/PuerkitoBio/goquery"
+	log "github.com/sirupsen/logrus"
+)
+
+// ErrCredentialsRequired is returned by operations which only work for a
+// logged-in user when the client has no credentials.
+var ErrCredentialsRequired = errors.New("hn: username and password are required")
+
+// Client retrieves HN listings and discussions.  It is safe for concurrent
+// use.
+type Client struct {
+	baseURL    string
+	username   string
+	password   string
+	httpClient *http.Client
+	throttle   *common.Throttle
+	store      *common.SessionStore
+	logger     log.FieldLogger
+
+	commentStates common.CommentStatePolicy
+
+	mu      sync.Mutex
+	session *common.Session
+}
+
+// New constructs a new Client.
+func New(opts ...Option) *Client {
+	c := &Client{
+		baseURL: common.BaseURL,
+		logger:  log.StandardLogger(),
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	if c.httpClient == nil {
+		c.httpClient = common.NoAuthClient()
+	} else {
+		// Avoid mutating the caller's client when logging in or throttling.
+		httpClient := *c.httpClient
+		c.httpClient = &httpClient
+	}
+	if c.throttle != nil {
+		// Wrap a copy of the caller's throttle settings so it may be passed
+		// to several clients, each with its own transport.
+		throttle := &common.Throttle{
+			Transport:   c.throttle.Transport,
+			MinInterval: c.throttle.MinInterval,
+			MaxRetries:  c.throttle.MaxRetries,
+			Backoff:     c.throttle.Backoff,
+			MaxBackoff:  c.throttle.MaxBackoff,
+		}
+		if throttle.Transport == nil {
+			throttle.Transport = c.httpClient.Transport
+		}
+		c.throttle = throttle
+		c.httpClient.Transport = throttle
+	}
+	return c
+}
+
+// Frontpage returns the stories on the front page.
+func (c *Client) Frontpage(ctx context.Context) (domain.Stories, error) {
+	return c.Listing(ctx, "/news")
+}
+
+// Favorites returns the favorite stories of the named user.
+func (c *Client) Favorites(ctx context.Context, user string) (domain.Stories, error) {
+	return c.Listing(ctx, "/favorites?id="+url.QueryEscape(user))
+}
+
+// Submissions returns the stories submitted by the named user.
+func (c *Client) Submissions(ctx context.Context, user string) (domain.Stories, error) {
+	return c.Listing(ctx, "/submitted?id="+url.QueryEscape(user))
+}
+
+// UserComments returns the comment history of the named user, along with the
+// visible replies to each comment.  On error or cancellation of ctx, the
+// comments collected so far are returned alongside it.
+func (c *Client) UserComments(ctx context.Context, user string) ([]domain.UserComment, error) {
+	session, err := c.login(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	crawler := &common.Crawler{
+		Client:  c.httpClient,
+		Session: session,
+		URL:     c.baseURL + "/threads?id=" + url.QueryEscape(user),
+		Logger:  c.logger,
+	}
+	comments, _, err := crawler.UserComments(ctx)
+	return comments, err
+}
+
+// Upvoted returns the stories upvoted by the logged-in user.
+func (c *Client) Upvoted(ctx context.Context) (domain.Stories, error) {
+	if session, err := c.login(ctx); err != nil {
+		return nil, err
+	} else if session == nil {
+		return nil, ErrCredentialsRequired
+	}
+	return c.Listing(ctx, "/upvoted?id="+url.QueryEscape(c.username))
+}
+
+// Listing returns all stories of the paginated listing at the specified path
+// (e.g. "/ask").  On error or cancellation of ctx, the stories collected so far
+// are returned alongside the error.  An empty listing yields no stories, but
+// a continuation page without stories yields common.ErrEmptyPage.
+func (c *Client) Listing(ctx context.Context, path string) (domain.Stories, error) {
+	session, err := c.login(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	crawler := &common.Crawler{
+		Client:  c.httpClient,
+		Session: session,
+		URL:     c.baseURL + path,
+		Logger:  c.logger,
+	}
+	stories, _, err := crawler.Stories(ctx)
+	return stories, err
+}
+
+// User returns the profile of the named user.  common.ErrNoSuchUser is
+// returned when there is no such user.
+func (c *Client) User(ctx context.Context, name string) (domain.User, error) {
+	doc, err := c.getDocument(ctx, fmt.Sprintf("%v/user?id=%v", c.baseURL, url.QueryEscape(name)))
+	if err != nil {
+		return domain.User{}, err
+	}
+	return common.ExtractUser(doc.Selection, c.baseURL)
+}
+
+// Item returns the discussion threads of the item with the specified ID,
+// following the "More" links of discussions spanning several pages.  See
+// Story for the warnings.
+func (c *Client) Item(ctx context.Context, id int64) (domain.Threads, []common.ParseWarning, error) {
+	story, warnings, err := c.Story(ctx, id)
+	return story.Children, warnings, err
+}
+
+// Story returns the item with the specified ID along with its complete
+// discussion.  Comments with inconsistent nesting are re-parented and reported
+// in the returned warnings; extraction was lossless when there are none.  On
+// error the partially retrieved story is returned.
+func (c *Client) Story(ctx context.Context, id int64) (domain.Story, []common.ParseWarning, error) {
+	discussion := common.NewDiscussion()
+	discussion.Lenient = true
+	discussion.States = c.commentStates
+	story, err := discussion.Fetch(ctx, c.getDocument, fmt.Sprintf("%v/item?id=%v", c.baseURL, id))
+	return story, discussion.Warnings, err
+}
+
+// Discussions fetches the discussion of each story concurrently, through at
+// most workers requests at a time, and attaches it to the story in place.
+// Dead, flagged and deleted comments are handled as set by WithCommentStates.
+// Failures don't stop the other fetches and are returned keyed by story ID,
+// as are the parse warnings of the discussions which were fetched.
+func (c *Client) Discussions(ctx context.Context, stories domain.Stories, workers int) (map[int64]error, map[int64][]common.ParseWarning) {
+	return common.FetchDiscussions(ctx, c.getDocument, stories, workers, c.commentStates)
+}
+
+// getDocument retrieves and parses the specified page, through the logged-in
+// session if credentials were configured.
+func (c *Client) getDocument(ctx context.Context, page string) (*goquery.Document, error) {
+	session, err := c.login(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if session != nil {
+		return session.GetDocument(ctx, page)
+	}
+	return common.GetDocument(ctx, c.httpClient, page)
+}
+
+// login returns the logged-in session, logging in first if necessary.  A nil
+// session is returned when no credentials (or saved session) are available.
+func (c *Client) login(ctx context.Context) (*common.Session, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	if !c.authenticated() {
+		return nil, nil
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.session == nil {
+		session := &common.Session{
+			Client:   c.httpClient,
+			BaseURL:  c.baseURL,
+			Username: c.username,
+			Password: c.password,
+			Store:    c.store,
+		}
+		if err := session.Open(ctx); err == common.ErrNoCredentials {
+			// No saved session to fall back on, so browse anonymously.
+			return nil, nil
+		} else if err != nil {
+			return nil, err
+		}
+		c.session = session
+	}
+	return c.session, nil
+}
+
+// authenticated returns true when the client has credentials or may have a
+// saved session to use.
+func (c *Client) authenticated() bool {
+	return c.username != "" && (c.password != "" || c.store != nil)
+}
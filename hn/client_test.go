@@ -0,0 +1,105 @@
+package hn
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/jaytaylor/hn-utils/common"
+)
+
+const frontpageHTML = `<html><body><table>
+<tr class="athing" id="%[1]v"><td class="title"><a class="storylink" href="https://example.com/%[1]v">Story %[1]v</a></td></tr>
+<tr><td class="subtext"><span class="score">10 points</span> <a href="user?id=pg" class="hnuser">pg</a> <a href="item?id=%[1]v">3 comments</a></td></tr>
+%[2]v
+</table></body></html>`
+
+func TestClientFrontpage(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if req.URL.Query().Get("p") == "2" {
+			fmt.Fprintf(w, frontpageHTML, 2, "")
+			return
+		}
+		fmt.Fprintf(w, frontpageHTML, 1, `<tr><td><a href="news?p=2" class="morelink">More</a></td></tr>`)
+	}))
+	defer server.Close()
+
+	stories, err := New(WithBaseURL(server.URL)).Frontpage(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected, actual := 2, len(stories); actual != expected {
+		t.Fatalf("Expected len(stories)=%v but actual=%v", expected, actual)
+	}
+	for i, story := range stories {
+		if expected, actual := int64(i+1), story.ID; actual != expected {
+			t.Errorf("Expected stories[%v].ID=%v but actual=%v", i, expected, actual)
+		}
+	}
+}
+
+func TestClientEmptyListing(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		fmt.Fprint(w, `<html><body><table></table></body></html>`)
+	}))
+	defer server.Close()
+
+	stories, err := New(WithBaseURL(server.URL)).Favorites(context.Background(), "pg")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected, actual := 0, len(stories); actual != expected {
+		t.Errorf("Expected len(stories)=%v but actual=%v", expected, actual)
+	}
+}
+
+func TestClientUpvotedRequiresCredentials(t *testing.T) {
+	if _, err := New().Upvoted(context.Background()); err != ErrCredentialsRequired {
+		t.Fatalf("Expected err=%v but actual=%v", ErrCredentialsRequired, err)
+	}
+}
+
+func TestClientUser(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		fmt.Fprintf(w, `<html><body><table><tr><td>user:</td><td><a href="user?id=%[1]v" class="hnuser">%[1]v</a></td></tr><tr><td>karma:</td><td>42</td></tr></table></body></html>`, req.URL.Query().Get("id"))
+	}))
+	defer server.Close()
+
+	user, err := New(WithBaseURL(server.URL)).User(context.Background(), "pg")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected, actual := server.URL+"/submitted?id=pg", user.SubmissionsURL; actual != expected {
+		t.Errorf("Expected user.SubmissionsURL=%q but actual=%q", expected, actual)
+	}
+	if expected, actual := server.URL+"/favorites?id=pg", user.FavoritesURL; actual != expected {
+		t.Errorf("Expected user.FavoritesURL=%q but actual=%q", expected, actual)
+	}
+}
+
+func TestClientSharedThrottle(t *testing.T) {
+	var (
+		throttle = &common.Throttle{MaxRetries: 1}
+		first    = &http.Transport{}
+		second   = &http.Transport{}
+	)
+
+	New(WithHTTPClient(&http.Client{Transport: first}), WithThrottle(throttle))
+	c := New(WithHTTPClient(&http.Client{Transport: second}), WithThrottle(throttle))
+
+	if throttle.Transport != nil {
+		t.Errorf("Expected shared throttle.Transport=nil but actual=%v", throttle.Transport)
+	}
+	wrapped, ok := c.httpClient.Transport.(*common.Throttle)
+	if !ok {
+		t.Fatalf("Expected *common.Throttle transport but actual=%T", c.httpClient.Transport)
+	}
+	if wrapped.Transport != http.RoundTripper(second) {
+		t.Errorf("Expected second client to wrap its own transport but actual=%v", wrapped.Transport)
+	}
+	if expected, actual := 1, wrapped.MaxRetries; actual != expected {
+		t.Errorf("Expected MaxRetries=%v but actual=%v", expected, actual)
+	}
+}
@@ -0,0 +1,71 @@
+package hn
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/jaytaylor/hn-utils/common"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// Option configures a Client.
+type Option func(*Client)
+
+// WithBaseURL overrides the HN base URL (defaults to common.BaseURL).
+func WithBaseURL(baseURL string) Option {
+	return func(c *Client) {
+		c.baseURL = strings.TrimSuffix(baseURL, "/")
+	}
+}
+
+// WithCredentials sets the username and password used to log in.  Without
+// credentials the client browses anonymously.
+func WithCredentials(username string, password string) Option {
+	return func(c *Client) {
+		c.username = username
+		c.password = password
+	}
+}
+
+// WithHTTPClient sets the underlying *http.Client.  The client is copied, so
+// logging in and throttling never modify the passed instance.
+func WithHTTPClient(httpClient *http.Client) Option {
+	return func(c *Client) {
+		c.httpClient = httpClient
+	}
+}
+
+// WithLogger sets the logger used by the client (defaults to the standard
+// logrus logger).
+func WithLogger(logger log.FieldLogger) Option {
+	return func(c *Client) {
+		c.logger = logger
+	}
+}
+
+// WithThrottle spaces out requests and retries failed or rate-limited ones
+// according to the settings of t, wrapping the transport of the underlying
+// *http.Client.  t itself is left untouched and may be shared by clients.
+func WithThrottle(t *common.Throttle) Option {
+	return func(c *Client) {
+		c.throttle = t
+	}
+}
+
+// WithSessionStore reuses login sessions saved in store and saves new ones to
+// it.  With a store, credentials may omit the password as long as a valid
+// session for the user has been saved.
+func WithSessionStore(store *common.SessionStore) Option {
+	return func(c *Client) {
+		c.store = store
+	}
+}
+
+// WithCommentStates sets what happens to dead, flagged and deleted comments
+// in discussions (defaults to common.MarkCommentStates).
+func WithCommentStates(policy common.CommentStatePolicy) Option {
+	return func(c *Client) {
+		c.commentStates = policy
+	}
+}